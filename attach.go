@@ -0,0 +1,106 @@
+package configkit
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// AttachTo integrates the Loader into an existing cobra command instead of building its own root command.
+//
+// It registers the built-in flags, affecting the config loading (--config, --config-format, --set, and --env and
+// --env-prefix, if enabled), as persistent flags of rootCmd, and loads the config into cfg in its
+// PersistentPreRunE, so the config is ready once rootCmd (or any of its subcommands) runs.
+// The loading is the same Load performs: config files, env variables, validation and the
+// WithPreRun/WithPostRun hooks. An existing PersistentPreRunE (or PersistentPreRun) of rootCmd is kept
+// and runs after the config is loaded.
+//
+// Everything else is up to the caller's command: --version, --check-config, --print-config,
+// the config command (WithValidateCommand), subcommands (AddCommand) and the entrypoint (WithRunE) are not set up.
+// Loading errors are returned by rootCmd.Execute.
+//
+// rootCmd and its subcommands must not define flags, colliding by name or shorthand with the built-in ones.
+// The check is done on attach, so subcommands should be added to rootCmd beforehand.
+// Note: if a subcommand defines its own PersistentPreRun(E), cobra runs it instead of the config loading.
+func (l *Loader) AttachTo(rootCmd *cobra.Command, cfg any) error {
+	if rootCmd == nil {
+		return fmt.Errorf("rootCmd must be a non-nil command")
+	}
+	if err := validateTarget(cfg); err != nil {
+		return err
+	}
+	if err := l.validateEnvPrefix(l.envPrefix); err != nil {
+		return err
+	}
+
+	flags := l.persistentFlags()
+	if err := checkFlagCollisions([]*cobra.Command{rootCmd}, flags); err != nil {
+		return err
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		_ = flags.SetAnnotation(f.Name, builtinFlagAnnotation, nil) // Flag exists.
+	})
+	rootCmd.PersistentFlags().AddFlagSet(flags)
+	_ = rootCmd.RegisterFlagCompletionFunc(setKey, l.completeSetKeys(cfg)) // Flag exists and is new.
+
+	preRunE, preRun := rootCmd.PersistentPreRunE, rootCmd.PersistentPreRun
+	rootCmd.PersistentPreRun = nil
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		// Built-in help command and shell completion requests don't need the config.
+		if c.Name() == "help" && c.Parent() == rootCmd || isCompletionRequest(c) {
+			return nil
+		}
+
+		if err := l.loadAttached(rootCmd, cfg); err != nil {
+			return err
+		}
+
+		switch {
+		case preRunE != nil:
+			return preRunE(c, args)
+		case preRun != nil:
+			preRun(c, args)
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// loadAttached loads the config into cfg on execution of the command, the Loader is attached to.
+// A new viper instance is used on each execution, so the command may be executed multiple times.
+func (l *Loader) loadAttached(rootCmd *cobra.Command, cfg any) error {
+	// Warnings go to the error output of the command. Setting it on a copy, so the Loader itself stays stateless.
+	lc := *l
+	lc.warnWriter = rootCmd.ErrOrStderr()
+	l = &lc
+
+	v := l.newViper()
+	if err := l.bindPersistentFlags(v, rootCmd.PersistentFlags()); err != nil {
+		return err
+	}
+
+	for _, hook := range l.preRun {
+		if err := hook(); err != nil {
+			return fmt.Errorf("pre-run: %w", err)
+		}
+	}
+
+	if err := l.loadConfig(v, cfg); err != nil {
+		return err
+	}
+	if err := l.validate(cfg); err != nil {
+		return err
+	}
+	l.rememberLoad(v, cfg)
+	l.flags.remember(rootCmd.PersistentFlags())
+
+	for _, hook := range l.postRun {
+		if err := hook(); err != nil {
+			return fmt.Errorf("post-run: %w", err)
+		}
+	}
+
+	return nil
+}
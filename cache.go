@@ -0,0 +1,104 @@
+package configkit
+
+import (
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fileCache holds the parsed config files, keyed by path. An entry is valid while the file's
+// modification time and size (as well as the format it was decoded as) stay the same. It is safe for concurrent use.
+type fileCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedFile
+	reads   atomic.Int64 // Number of files actually read, for tests and diagnostics.
+}
+
+// cachedFile is a parsed config file along with the attributes of the file it was parsed from.
+type cachedFile struct {
+	modTime  time.Time
+	size     int64
+	format   string
+	settings map[string]any
+}
+
+// newFileCache returns an empty file cache.
+func newFileCache() *fileCache {
+	return &fileCache{entries: make(map[string]cachedFile)}
+}
+
+// get returns a copy of the cached settings of the file at path, decoded as format,
+// if the file hasn't changed since it was cached.
+func (c *fileCache) get(path string, info os.FileInfo, format string) (map[string]any, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[path]
+	if !ok || !entry.modTime.Equal(info.ModTime()) || entry.size != info.Size() || entry.format != format {
+		return nil, false
+	}
+	return copySettings(entry.settings), true
+}
+
+// put caches a copy of the settings of the file at path, decoded as format.
+func (c *fileCache) put(path string, info os.FileInfo, format string, settings map[string]any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[path] = cachedFile{modTime: info.ModTime(), size: info.Size(), format: format, settings: copySettings(settings)}
+}
+
+// mergeCachedFile merges the config file at path into v, parsing it only if it has changed since the last read.
+// The file is decoded as format. Malformed files result in ConfigParseError.
+func (l *Loader) mergeCachedFile(v *viper.Viper, path, format string) error {
+	info, err := l.stat(path)
+	if err != nil {
+		return err
+	}
+	if settings, ok := l.fileCache.get(path, info, format); ok {
+		l.log().Debug("config file unchanged, using cached", "path", path)
+		return v.MergeConfigMap(settings)
+	}
+
+	data, err := l.readFile(path)
+	if err != nil {
+		return err
+	}
+	l.fileCache.reads.Add(1)
+
+	settings, err := l.decodeData(data, format)
+	if err != nil {
+		return asParseError(path, err)
+	}
+	l.fileCache.put(path, info, format, settings)
+
+	return v.MergeConfigMap(settings)
+}
+
+// copySettings returns a deep copy of the nested settings map, so the cached maps are never shared.
+func copySettings(settings map[string]any) map[string]any {
+	result := make(map[string]any, len(settings))
+	for key, value := range settings {
+		result[key] = copySetting(value)
+	}
+	return result
+}
+
+// copySetting returns a deep copy of the maps and slices of value. Other values are returned as is.
+func copySetting(value any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		return copySettings(value)
+	case []any:
+		result := make([]any, len(value))
+		for i, item := range value {
+			result[i] = copySetting(item)
+		}
+		return result
+	default:
+		return value
+	}
+}
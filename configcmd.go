@@ -0,0 +1,51 @@
+package configkit
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// newConfigCommand returns the built-in `config` command with the `validate [path]` subcommand.
+//
+// The config is not loaded before the command runs: the file to validate may differ from the configured one.
+func (l *Loader) newConfigCommand(v *viper.Viper, cfg any, writer io.Writer) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Configuration tools",
+		// Overrides the root pre-run, so the configured config is not loaded.
+		PersistentPreRunE: func(*cobra.Command, []string) error { return nil },
+	}
+
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "validate [path]",
+		Short: "Validate the configuration file (default: the configured one), then exit",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				// The file, given explicitly, must exist, even if the config file is optional.
+				if _, err := l.stat(args[0]); err != nil {
+					return fmt.Errorf("validate config: %w", err)
+				}
+				v.Set("config", args[0])
+			}
+
+			// Decoding into a fresh value, so cfg is left intact.
+			fresh := reflect.New(reflect.TypeOf(cfg).Elem()).Interface()
+			if err := l.loadConfig(v, fresh); err != nil {
+				return fmt.Errorf("validate config: %w", err)
+			}
+			if err := l.validate(fresh); err != nil {
+				return err
+			}
+
+			_, err := fmt.Fprintln(writer, "OK")
+			return err
+		},
+	})
+
+	return configCmd
+}
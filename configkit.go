@@ -18,6 +18,8 @@
 //	// continue with initialized config
 package configkit
 
+import "strconv"
+
 // LoadResult indicates the outcome of the Load operation.
 type LoadResult int
 
@@ -27,4 +29,79 @@ const (
 
 	// LoadResultStop means the program should stop (e.g. after --help or --version).
 	LoadResultStop
+
+	// LoadResultHelp means the program should stop after --help.
+	// Returned instead of LoadResultStop only if WithDetailedResults is used.
+	LoadResultHelp
+
+	// LoadResultVersion means the program should stop after --version.
+	// Returned instead of LoadResultStop only if WithDetailedResults is used.
+	LoadResultVersion
+
+	// LoadResultCheckConfig means the program should stop after --check-config.
+	// Returned instead of LoadResultStop only if WithDetailedResults is used.
+	LoadResultCheckConfig
+
+	// LoadResultCommand means the program should stop after a subcommand was executed.
+	// Returned instead of LoadResultStop only if WithDetailedResults is used.
+	LoadResultCommand
+
+	// LoadResultPrintConfig means the program should stop after --print-config.
+	// Returned instead of LoadResultStop only if WithDetailedResults is used.
+	LoadResultPrintConfig
 )
+
+// IsStop reports whether the program should stop, regardless of the reason.
+func (r LoadResult) IsStop() bool {
+	return r != LoadResultContinue
+}
+
+// String returns the name of the result (e.g., "continue", "stop"), so it reads well in logs and test output.
+// Unknown values are formatted as LoadResult(N).
+func (r LoadResult) String() string {
+	switch r {
+	case LoadResultContinue:
+		return "continue"
+	case LoadResultStop:
+		return "stop"
+	case LoadResultHelp:
+		return "help"
+	case LoadResultVersion:
+		return "version"
+	case LoadResultCheckConfig:
+		return "check-config"
+	case LoadResultCommand:
+		return "command"
+	case LoadResultPrintConfig:
+		return "print-config"
+	default:
+		return "LoadResult(" + strconv.Itoa(int(r)) + ")"
+	}
+}
+
+// MarshalText returns the name of the result (see String), so it is encoded by name, e.g., in the JSON LoadReport.
+func (r LoadResult) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+// ShouldExit maps the outcome of Load to the process exit code, standardizing the CLI behavior of main():
+//   - error: exit with code 1 (reporting the error is up to the caller);
+//   - any stop result (e.g. after --help or --version): exit with code 0;
+//   - LoadResultContinue: don't exit, the program should proceed.
+//
+// Example:
+//
+//	result, err := loader.Load(&cfg, configkit.PlainVersionPrinter("v1.0.0"), os.Stdout)
+//	if code, exit := configkit.ShouldExit(result, err); exit {
+//	    os.Exit(code)
+//	}
+func ShouldExit(result LoadResult, err error) (code int, exit bool) {
+	switch {
+	case err != nil:
+		return 1, true
+	case result.IsStop():
+		return 0, true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,26 @@
+// Package configkittest provides helpers for testing the code, which depends on configkit.
+//
+// The helpers populate the config without touching files, env variables or os.Args,
+// so tests stay isolated from the global state.
+//
+// Example:
+//
+//	cfg := &Config{}
+//	err := configkittest.LoadFromMap(cfg, map[string]any{
+//	    "port": 8080,
+//	    "db":   map[string]any{"url": "postgres://localhost"},
+//	})
+package configkittest
+
+import "github.com/Averlex/configkit"
+
+// LoadFromMap populates cfg from m (nested maps for nested keys), decoding it the same way configkit.Loader does:
+// struct tags (defaults, required fields, etc.) and decode hooks (e.g., "5s" to time.Duration) apply.
+//
+// Env variables are ignored. opts are applied to the underlying Loader (e.g., configkit.WithValidator).
+func LoadFromMap(cfg any, m map[string]any, opts ...configkit.Option) error {
+	opts = append(opts, configkit.WithoutEnv())
+	loader := configkit.NewLoader("configkittest", "", "", "", "", opts...)
+	_, err := loader.LoadFromMap(cfg, m)
+	return err
+}
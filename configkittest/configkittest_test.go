@@ -0,0 +1,111 @@
+package configkittest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Averlex/configkit"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigkitTestSuite struct {
+	suite.Suite
+}
+
+func TestConfigkitTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigkitTestSuite))
+}
+
+func (s *ConfigkitTestSuite) TestLoadFromMap() {
+	type testConfig struct {
+		Port     int           `mapstructure:"port" default:"8080"`
+		LogLevel string        `mapstructure:"log_level"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+		DB       struct {
+			URL string `mapstructure:"url" required:"true"`
+		} `mapstructure:"db"`
+	}
+
+	errInvalid := errors.New("invalid config")
+
+	testCases := []struct {
+		name           string
+		values         map[string]any
+		env            map[string]string
+		opts           []configkit.Option
+		expected       testConfig
+		expectedErrors []string
+		expectedError  error
+	}{
+		{
+			name: "nested values",
+			values: map[string]any{
+				"log_level": "debug",
+				"timeout":   "5s",
+				"db":        map[string]any{"url": "postgres://localhost"},
+			},
+			expected: func() testConfig {
+				cfg := testConfig{Port: 8080, LogLevel: "debug", Timeout: 5 * time.Second}
+				cfg.DB.URL = "postgres://localhost"
+				return cfg
+			}(),
+		},
+		{
+			name: "env ignored",
+			values: map[string]any{
+				"port": 9090,
+				"db":   map[string]any{"url": "postgres://localhost"},
+			},
+			env: map[string]string{"PORT": "7070", "DB_URL": "postgres://env", "_PORT": "7070"},
+			expected: func() testConfig {
+				cfg := testConfig{Port: 9090}
+				cfg.DB.URL = "postgres://localhost"
+				return cfg
+			}(),
+		},
+		{
+			name:           "required field missing",
+			values:         map[string]any{},
+			expectedErrors: []string{"'db.url' is required"},
+		},
+		{
+			name:           "validator applied",
+			values:         map[string]any{"db": map[string]any{"url": "postgres://localhost"}},
+			opts:           []configkit.Option{configkit.WithValidator(func(any) error { return errInvalid })},
+			expectedErrors: []string{errInvalid.Error()},
+			expectedError:  errInvalid,
+		},
+		{
+			name:           "undecodable value",
+			values:         map[string]any{"port": "not a number", "db": map[string]any{"url": "postgres://localhost"}},
+			expectedErrors: []string{"port"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			cfg := &testConfig{}
+			err := LoadFromMap(cfg, tC.values, tC.opts...)
+
+			if len(tC.expectedErrors) > 0 {
+				for _, msg := range tC.expectedErrors {
+					s.Require().ErrorContains(err, msg, "unexpected error")
+				}
+				if tC.expectedError != nil {
+					s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+				}
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+
+	s.Run("non-pointer config", func() {
+		s.Require().Error(LoadFromMap(testConfig{}, map[string]any{}), "expected error, got nil")
+	})
+}
@@ -0,0 +1,140 @@
+package configkit
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// unmarshal decodes the merged config from v into cfg according to the Loader options.
+func (l *Loader) unmarshal(v *viper.Viper, cfg any) error {
+	l.applyDeprecatedKeys(v)
+	l.applyDefaults(v, cfg)
+	l.bindStructEnv(v, cfg)
+	l.bindEnvFold(v, l.envKeys(v, cfg))
+	// The built-in keys are bound before the config is read (see loadConfig).
+	l.bindEnvFallbacks(v, slices.DeleteFunc(l.envKeys(v, cfg), func(key string) bool {
+		return slices.Contains(l.builtinKeys(), key)
+	}))
+	if l.envExpansion {
+		if err := l.expandEnv(v); err != nil {
+			return err
+		}
+	}
+	if err := l.applyEnvFiles(v, cfg); err != nil {
+		return err
+	}
+	if err := l.applyJSONEnv(v, cfg); err != nil {
+		return err
+	}
+	l.applyEnvTags(v, cfg)
+	if err := l.applySetOverrides(v); err != nil {
+		return err
+	}
+	if err := l.applyTimeFormats(v, cfg); err != nil {
+		return err
+	}
+	if err := l.applySizes(v, cfg); err != nil {
+		return err
+	}
+	if l.logger != nil {
+		l.logOverrides(v, cfg)
+	}
+
+	var md mapstructure.Metadata
+	if err := v.Unmarshal(cfg, l.decoderOptions(&md)...); err != nil {
+		return err
+	}
+	if l.trimSpace {
+		l.trimStrings(cfg)
+	}
+
+	if l.strictKeys {
+		// Deprecated keys are expected to remain unused, as their values are copied to the new ones.
+		known := append(l.builtinKeys(), slices.Collect(maps.Keys(l.deprecatedKeys))...)
+		if err := checkUnusedKeys(md.Unused, known); err != nil {
+			return err
+		}
+	}
+	if l.strictEnv {
+		if err := l.checkUnknownEnv(v, cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// bindStructEnv binds the env variables to the config keys of cfg, nested at any depth
+// (e.g., db.replica.url → PREFIX_DB_REPLICA_URL).
+//
+// AutomaticEnv alone applies only to the keys, known to v, so the keys absent from the config files
+// would be missed otherwise. Map fields are skipped: binding them would shadow their nested keys.
+func (l *Loader) bindStructEnv(v *viper.Viper, cfg any) {
+	if l.envDisabled {
+		return
+	}
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		if field.Type.Kind() != reflect.Map {
+			_ = v.BindEnv(key) // Never fails with a key given.
+		}
+	})
+}
+
+// decoderOptions returns mapstructure decoder options according to the Loader options.
+// Decoding metadata is collected into md. Standard hooks are run before the viper's decode hooks
+// (time.Duration and comma-separated slices by default), so net.IP is not split as a slice.
+func (l *Loader) decoderOptions(md *mapstructure.Metadata) []viper.DecoderConfigOption {
+	return []viper.DecoderConfigOption{
+		func(dc *mapstructure.DecoderConfig) {
+			dc.Metadata = md
+			dc.WeaklyTypedInput = l.weaklyTyped
+			dc.TagName = l.tagName
+			hooks := standardHooks()
+			if dc.DecodeHook != nil {
+				hooks = append(hooks, dc.DecodeHook)
+			}
+			dc.DecodeHook = mapstructure.ComposeDecodeHookFunc(hooks...)
+		},
+	}
+}
+
+// standardHooks returns decode hooks for the common types, decoded from their string representations:
+// time.Time (RFC3339 or date-only, as well as TOML dates), time.Duration (also from numbers as nanoseconds), net.IP,
+// *url.URL and ByteSize, as well as any type, implementing encoding.TextUnmarshaler (e.g., a custom log level enum).
+// The latter is the last one, so the types above are decoded by their own hooks.
+func standardHooks() []mapstructure.DecodeHookFunc {
+	return []mapstructure.DecodeHookFunc{
+		stringToTimeHook(),
+		numberToDurationHook(),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.StringToIPHookFunc(),
+		mapstructure.StringToURLHookFunc(),
+		stringToByteSizeHook(),
+		mapstructure.TextUnmarshallerHookFunc(),
+	}
+}
+
+// checkUnusedKeys returns an error, listing all config keys which don't map to any struct field.
+//
+// Metadata is used instead of mapstructure's ErrorUnused, as viper also holds keys of the built-in flags,
+// which are not expected to be a part of the config struct. Such keys are skipped.
+func checkUnusedKeys(unused, builtin []string) error {
+	unknown := make([]string, 0, len(unused))
+	for _, key := range unused {
+		if !slices.Contains(builtin, key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	slices.Sort(unknown)
+	return fmt.Errorf("unknown config keys: %s", strings.Join(unknown, ", "))
+}
@@ -0,0 +1,77 @@
+package configkit
+
+import (
+	"reflect"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// defaultTag sets the default value of a config field (e.g., `default:"8080"`).
+// The value is decoded the same way env values are, so the field may be of any decodable type.
+const defaultTag = "default"
+
+// applyDefaults sets the defaults of cfg fields, tagged with `default:"..."`, as the viper defaults:
+// they have the lowest priority and apply only to the keys, not set in any other source.
+//
+// Fields of map[string]Struct values are walked per map entry (e.g., upstreams.<name>.timeout),
+// so each entry, present in the merged config, gets its own nested defaults.
+// Self-referential types are walked once per path, the same way the config keys are derived:
+// a nested struct (or map entry) of a type, already being walked, gets no defaults.
+func (l *Loader) applyDefaults(v *viper.Viper, cfg any) {
+	l.setDefaults(v, reflect.TypeOf(cfg), "", map[reflect.Type]bool{})
+}
+
+// setDefaults sets the defaults of the struct type t fields, prefixed with prefix, on v.
+// visited holds the struct types on the current path to break cycles.
+func (l *Loader) setDefaults(v *viper.Viper, t reflect.Type, prefix string, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	walkStructKeys(t, prefix, l.keyFormat(), func(key string, field reflect.StructField) {
+		if value, ok := field.Tag.Lookup(defaultTag); ok {
+			v.SetDefault(key, value)
+			return
+		}
+
+		elem, ok := structMapElem(field.Type)
+		if !ok {
+			return
+		}
+		entries := v.GetStringMap(key)
+		names := make([]string, 0, len(entries))
+		for name := range entries {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			l.setDefaults(v, elem, key+l.keyDelimiter+name+l.keyDelimiter, visited)
+		}
+	})
+}
+
+// structMapElem returns the struct element type of the map type t with string keys (e.g., map[string]Upstream
+// or map[string]*Upstream). ok is false for other types.
+func structMapElem(t reflect.Type) (elem reflect.Type, ok bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	elem = t.Elem()
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct || isLeafStruct(elem) {
+		return nil, false
+	}
+	return elem, true
+}
@@ -0,0 +1,42 @@
+package configkit
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// applyDeprecatedKeys copies the values of the deprecated keys of v, set by WithDeprecatedKeys, to their new keys,
+// warning about each deprecated key in use. If the new key is set as well, it wins.
+func (l *Loader) applyDeprecatedKeys(v *viper.Viper) {
+	for _, old := range slices.Sorted(maps.Keys(l.deprecatedKeys)) {
+		if !v.IsSet(old) {
+			continue
+		}
+		key := l.deprecatedKeys[old]
+		if v.IsSet(key) {
+			l.warn(fmt.Sprintf("config key %q is deprecated and ignored, as its replacement %q is set", old, key))
+			continue
+		}
+		v.Set(key, v.Get(old))
+		l.warn(fmt.Sprintf("config key %q is deprecated, use %q instead", old, key))
+	}
+}
+
+// warn reports a warning about the config: to the logger, if set, or to the writer of the load otherwise
+// (the one, passed to Load, or the error output of the command with AttachTo). The loads without a writer
+// (e.g., LoadFromReader or the reloads) report it to the logger only.
+// The warning is collected for the LoadReport as well, if the load is detailed.
+func (l *Loader) warn(msg string) {
+	if l.warnings != nil {
+		*l.warnings = append(*l.warnings, msg)
+	}
+	switch {
+	case l.logger != nil:
+		l.logger.Warn(msg)
+	case l.warnWriter != nil:
+		_, _ = fmt.Fprintf(l.warnWriter, "Warning: %s\n", msg)
+	}
+}
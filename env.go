@@ -0,0 +1,364 @@
+package configkit
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// envTag overrides the env variable name of a config field (e.g., `env:"LEGACY_DB_URL"`).
+const envTag = "env"
+
+// envFileSuffix marks env variables, holding a path to the file with the value (Docker secrets pattern).
+const envFileSuffix = "_FILE"
+
+// configB64Suffix marks the env variable, holding the whole base64-encoded config (PREFIX_CONFIG_B64).
+const configB64Suffix = "_B64"
+
+// getenv returns the value of the env variable name. With WithCaseInsensitiveEnv, the variable
+// with the name in another casing is used, if the exact one is not set (see lookupEnvFold).
+func (l *Loader) getenv(name string) string {
+	value, _ := l.lookupEnv(name)
+	return value
+}
+
+// lookupEnv acts like getenv, but also reports whether the variable is set. An empty variable counts as set
+// only with WithAllowEmptyEnv, the same way viper treats it.
+func (l *Loader) lookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok && (value != "" || l.allowEmptyEnv) {
+		return value, true
+	}
+	if !l.caseInsensitiveEnv {
+		return "", false
+	}
+	if actual, ok := l.lookupEnvFold(name); ok {
+		return os.Getenv(actual), true
+	}
+	return "", false
+}
+
+// envSet reports whether the env variable name is set (see lookupEnv).
+func (l *Loader) envSet(name string) bool {
+	_, ok := l.lookupEnv(name)
+	return ok
+}
+
+// lookupEnvFold returns the name of the set env variable, equal to name in another casing (e.g., TestApp_Port
+// for TESTAPP_PORT). If there are several ones, the first one in lexical order is returned, so the result is deterministic.
+func (l *Loader) lookupEnvFold(name string) (string, bool) {
+	var found []string
+	for _, kv := range os.Environ() {
+		actual, value, _ := strings.Cut(kv, "=")
+		if actual != name && (value != "" || l.allowEmptyEnv) && strings.EqualFold(actual, name) {
+			found = append(found, actual)
+		}
+	}
+	if len(found) == 0 {
+		return "", false
+	}
+	return slices.Min(found), true
+}
+
+// bindEnvFold binds the env variables, named after the keys in another casing, to the keys of v,
+// if WithCaseInsensitiveEnv is set. The upper case names still take precedence, as AutomaticEnv is checked first.
+func (l *Loader) bindEnvFold(v *viper.Viper, keys []string) {
+	if l.envDisabled || !l.caseInsensitiveEnv {
+		return
+	}
+	for _, key := range keys {
+		if actual, ok := l.lookupEnvFold(l.envVarName(v, key)); ok {
+			_ = v.BindEnv(key, actual) // Never fails with a key given.
+		}
+	}
+}
+
+// envKeys returns the sorted config keys, which values may be set by env variables: the keys of cfg
+// and the ones of WithEnvBindings. Unless WithExplicitEnvBindings is set, all the keys known to v are included too.
+func (l *Loader) envKeys(v *viper.Viper, cfg any) []string {
+	keys := append(structKeys(reflect.TypeOf(cfg), l.keyFormat()), l.envBindings...)
+	if !l.explicitEnv {
+		keys = append(keys, v.AllKeys()...)
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// bindEnvFallbacks binds the env variables with the fallback prefixes (see WithEnvPrefixFallback) to the keys,
+// not set with the main prefix. Only the set variables are bound, and a deprecation warning is reported for each.
+// The main name still takes precedence, as it is looked up first.
+func (l *Loader) bindEnvFallbacks(v *viper.Viper, keys []string) {
+	if l.envDisabled || len(l.fallbackPrefixes) == 0 {
+		return
+	}
+	for _, key := range keys {
+		name := l.envVarName(v, key)
+		if l.envSet(name) {
+			continue
+		}
+		for _, prefix := range l.fallbackPrefixes {
+			fallback := l.prefixedEnvName(prefix, key)
+			if !l.envSet(fallback) {
+				continue
+			}
+			_ = v.BindEnv(key, fallback) // Never fails with a key given.
+			l.warn(fmt.Sprintf("env variable %q is deprecated, use %q instead", fallback, name))
+			break
+		}
+	}
+}
+
+// applyEnvFiles sets the values of the config keys from the files, referenced by PREFIX_KEY_FILE env variables.
+//
+// The keys, which values may be set by env variables (see envKeys), are checked, except for the built-in ones.
+// If PREFIX_KEY itself is set, it wins.
+// Trailing newlines of the file contents are trimmed.
+func (l *Loader) applyEnvFiles(v *viper.Viper, cfg any) error {
+	if l.envDisabled {
+		return nil
+	}
+
+	for _, key := range l.envKeys(v, cfg) {
+		if slices.Contains(l.builtinKeys(), key) {
+			continue
+		}
+		name := l.envVarName(v, key)
+		path := l.getenv(name + envFileSuffix)
+		if path == "" || l.envSet(name) {
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read %s%s for '%s': %w", name, envFileSuffix, key, err)
+		}
+		v.Set(key, strings.TrimRight(string(data), "\r\n"))
+		l.log().Debug("read env value from file", "key", key, "env", name+envFileSuffix, "path", path)
+	}
+
+	return nil
+}
+
+// applyEnvTags sets the values of the cfg fields, tagged with `env:"NAME"`, from the NAME env variables.
+//
+// The name is used as is: neither the prefix nor the key replacer are applied. The tagged variable
+// takes precedence over the derived PREFIX_KEY one and its _FILE counterpart.
+func (l *Loader) applyEnvTags(v *viper.Viper, cfg any) {
+	if l.envDisabled {
+		return
+	}
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		name := field.Tag.Get(envTag)
+		if name == "" {
+			return
+		}
+		l.envTags.Store(strings.ToLower(key), name) // For KeySources, which gets no cfg.
+		if value, ok := l.lookupEnv(name); ok {
+			v.Set(key, value)
+			l.log().Debug("read env value by tag", "key", key, "env", name)
+		}
+	})
+}
+
+// envTagName returns the name of the set env variable, the key is bound to by its `env:"NAME"` tag
+// in the configs, loaded by the Loader so far. ok is false, if there is no such tag or the variable is not set.
+func (l *Loader) envTagName(key string) (name string, ok bool) {
+	tagged, found := l.envTags.Load(key)
+	if !found || l.envDisabled {
+		return "", false
+	}
+	name = tagged.(string)
+	return name, l.envSet(name)
+}
+
+// checkUnknownEnv returns an error, listing all env variables under the prefix of v (e.g., PREFIX_PROT),
+// which don't correspond to any config key: the keys, which values may be set by env variables (see envKeys),
+// the built-in and deprecated keys, along with their _FILE counterparts, PREFIX_CONFIG_B64, the `env:"NAME"` tags
+// and the sections, set to JSON objects (see applyJSONEnv).
+//
+// Nothing is checked without a prefix, as every env variable would be under it.
+func (l *Loader) checkUnknownEnv(v *viper.Viper, cfg any) error {
+	prefix := strings.ToUpper(v.GetEnvPrefix())
+	if l.envDisabled || prefix == "" {
+		return nil
+	}
+
+	// Names are matched in upper case with WithCaseInsensitiveEnv, and exactly otherwise.
+	normalize := func(name string) string {
+		if l.caseInsensitiveEnv {
+			return strings.ToUpper(name)
+		}
+		return name
+	}
+
+	known := map[string]bool{normalize(l.envVarName(v, "config") + configB64Suffix): true}
+	keys := slices.Concat(l.envKeys(v, cfg), l.builtinKeys(), slices.Collect(maps.Keys(l.deprecatedKeys)))
+	for _, key := range keys {
+		name := l.envVarName(v, key)
+		known[normalize(name)] = true
+		known[normalize(name+envFileSuffix)] = true
+	}
+	for _, key := range l.jsonEnvKeys(cfg) {
+		// Sections are known only if set to JSON objects (e.g., PREFIX_DB='{...}'), not to scalars.
+		if name, _, ok := l.lookupJSONEnv(v, key); ok {
+			known[normalize(name)] = true
+		}
+	}
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(_ string, field reflect.StructField) {
+		if name := field.Tag.Get(envTag); name != "" {
+			known[normalize(name)] = true
+		}
+	})
+
+	var unknown []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if match := normalize(name); strings.HasPrefix(match, prefix+"_") && !known[match] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+
+	slices.Sort(unknown)
+	return fmt.Errorf("unknown env variables: %s", strings.Join(unknown, ", "))
+}
+
+// jsonEnvKeys returns the sorted config keys of cfg, which may be set by a JSON object env variable at once
+// (e.g., PREFIX_DB='{"url": "...", "pool_size": 5}' for the db section): the nested structs and map fields.
+func (l *Loader) jsonEnvKeys(cfg any) []string {
+	var keys []string
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		if field.Type.Kind() == reflect.Map {
+			keys = append(keys, key)
+		}
+		keys = append(keys, parentKeys(key, l.keyDelimiter)...) // Sections are the parents of the leaf keys.
+	})
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// parentKeys returns the parent keys of the config key, outermost first (e.g., db.replica.url → db, db.replica).
+func parentKeys(key, delim string) []string {
+	parts := strings.Split(key, delim)
+	parents := make([]string, 0, len(parts)-1)
+	for i := 1; i < len(parts); i++ {
+		parents = append(parents, strings.Join(parts[:i], delim))
+	}
+	return parents
+}
+
+// lookupJSONEnv returns the JSON object of the env variable for the config key, if it holds one
+// (its value starts with "{"). Other values are not JSON objects and are skipped.
+func (l *Loader) lookupJSONEnv(v *viper.Viper, key string) (name, value string, ok bool) {
+	name = l.envVarName(v, key)
+	value, _ = l.lookupEnv(name)
+	value = strings.TrimSpace(value)
+	return name, value, strings.HasPrefix(value, "{")
+}
+
+// applyJSONEnv sets the values of the config sections (nested structs and maps) of cfg from the JSON objects
+// of their env variables (e.g., PREFIX_DB='{"url": "...", "pool_size": 5}' sets db.url and db.pool_size).
+//
+// The object is flattened into the leaf keys, so the keys it lacks keep their values from the other sources.
+// More specific env variables take precedence: PREFIX_DB_URL (or PREFIX_DB_URL_FILE) wins over PREFIX_DB,
+// and so does PREFIX_DB_REPLICA over PREFIX_DB. Invalid JSON fails the loading.
+func (l *Loader) applyJSONEnv(v *viper.Viper, cfg any) error {
+	if l.envDisabled {
+		return nil
+	}
+
+	// Applying the outer sections first, so the nested ones override them.
+	for _, key := range l.jsonEnvKeys(cfg) {
+		name, value, ok := l.lookupJSONEnv(v, key)
+		if !ok {
+			continue
+		}
+
+		leaves, err := l.jsonEnvLeaves(key, value)
+		if err != nil {
+			return fmt.Errorf("'%s' decode %s as JSON: %w", key, name, err)
+		}
+
+		// AutomaticEnv shadows all the keys of the section by its env variable, so the values of the keys,
+		// absent from the object, are carried over from the other sources explicitly.
+		var rest []string
+		for _, leaf := range v.AllKeys() {
+			if _, ok := leaves[leaf]; !ok && strings.HasPrefix(leaf, key+l.keyDelimiter) {
+				rest = append(rest, leaf)
+			}
+		}
+		maps.Copy(leaves, l.valuesWithoutEnv(v, rest))
+
+		for leaf, leafValue := range leaves {
+			leafName := l.envVarName(v, leaf)
+			if l.envSet(leafName) || l.getenv(leafName+envFileSuffix) != "" {
+				continue
+			}
+			v.Set(leaf, leafValue)
+		}
+		l.log().Debug("read env value as JSON", "key", key, "env", name)
+	}
+	return nil
+}
+
+// noEnvReplacer maps every env variable name to the one, which can't be set ("=" is not allowed in the names).
+var noEnvReplacer = strings.NewReplacer("", "=")
+
+// valuesWithoutEnv returns the set values of the keys of v from all the sources but env variables.
+// The env lookups of v are turned off meanwhile with noEnvReplacer.
+func (l *Loader) valuesWithoutEnv(v *viper.Viper, keys []string) map[string]any {
+	v.SetEnvKeyReplacer(noEnvReplacer)
+	defer v.SetEnvKeyReplacer(l.envReplacer())
+
+	values := make(map[string]any, len(keys))
+	for _, key := range keys {
+		if value := v.Get(key); value != nil {
+			values[key] = value
+		}
+	}
+	return values
+}
+
+// jsonEnvLeaves decodes the JSON object value of the env variable for the config section key into its leaf values.
+func (l *Loader) jsonEnvLeaves(key, value string) (map[string]any, error) {
+	var object map[string]any
+	if err := json.Unmarshal([]byte(value), &object); err != nil {
+		return nil, err
+	}
+	return flattenMap(object, key, l.keyDelimiter), nil
+}
+
+// jsonEnvSet reports whether the config key is set by the JSON object env variable of any of its parents.
+func (l *Loader) jsonEnvSet(v *viper.Viper, key string) bool {
+	for _, parent := range parentKeys(key, l.keyDelimiter) {
+		if _, value, ok := l.lookupJSONEnv(v, parent); ok {
+			leaves, _ := l.jsonEnvLeaves(parent, value) // Invalid JSON fails the load before the sources may be reported.
+			if _, ok := leaves[key]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flattenMap returns the leaf values of the nested map m, keyed by their paths, prefixed with prefix
+// and joined with delim (e.g., {"a": {"b": 1}} → prefix.a.b: 1). Empty maps are leaves too.
+func flattenMap(m map[string]any, prefix, delim string) map[string]any {
+	leaves := make(map[string]any)
+	for key, value := range m {
+		path := prefix + delim + strings.ToLower(key)
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			maps.Copy(leaves, flattenMap(nested, path, delim))
+			continue
+		}
+		leaves[path] = value
+	}
+	return leaves
+}
@@ -0,0 +1,68 @@
+package configkit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/spf13/viper"
+)
+
+// ConfigParseError is returned when a config file is malformed.
+//
+// Use errors.As to retrieve it from the error, returned by Load.
+type ConfigParseError struct {
+	Path string // Path of the malformed config file.
+	Line int    // Line of the first error, 1-based. Zero if unknown.
+	Err  error  // Underlying decoder error.
+}
+
+// Error returns the formatted parse error.
+func (e *ConfigParseError) Error() string {
+	return fmt.Sprintf("parse config %q: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying decoder error.
+func (e *ConfigParseError) Unwrap() error {
+	return e.Err
+}
+
+// FieldError is a validation error of a single config field, carrying its config key (e.g., db.url),
+// so the key may be reported apart from the message (e.g., by --config-check-only).
+//
+// The message is the one of Err, which is expected to mention the key. Use errors.As to retrieve it.
+type FieldError struct {
+	Key string // Config key of the field.
+	Err error  // Underlying validation error.
+}
+
+// Error returns the message of the underlying error.
+func (e *FieldError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying validation error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// lineRe matches the line number in yaml errors: both "yaml: line 3: ..." and "line 3: cannot unmarshal ...",
+// as well as in HCL errors: "At 3:9: ...".
+var lineRe = regexp.MustCompile(`\bline (\d+)\b|\bAt (\d+):\d+`)
+
+// asParseError converts viper's parse error to ConfigParseError for the file at path.
+// Other errors are returned as is.
+func asParseError(path string, err error) error {
+	var parseErr viper.ConfigParseError
+	if !errors.As(err, &parseErr) {
+		return err
+	}
+
+	cause := parseErr.Unwrap()
+	result := &ConfigParseError{Path: path, Err: cause}
+	if m := lineRe.FindStringSubmatch(cause.Error()); m != nil {
+		result.Line, _ = strconv.Atoi(m[1] + m[2]) // Only one of the groups matches digits, conversion never fails.
+	}
+	return result
+}
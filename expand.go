@@ -0,0 +1,99 @@
+package configkit
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// expandEnv replaces ${VAR} references in the string values of v with the values of the env variables.
+// The built-in keys and the keys, overridden by env variables, are skipped.
+func (l *Loader) expandEnv(v *viper.Viper) error {
+	builtin := l.builtinKeys()
+	for _, key := range v.AllKeys() {
+		if slices.Contains(builtin, key) || (!l.envDisabled && l.envSet(l.envVarName(v, key))) {
+			continue
+		}
+		value, changed, err := expandValue(v.Get(key))
+		if err != nil {
+			return fmt.Errorf("'%s' expand env: %w", key, err)
+		}
+		if changed {
+			v.Set(key, value)
+		}
+	}
+	return nil
+}
+
+// expandValue expands the env references in value: a string, or strings nested in slices and maps.
+// changed reports whether value contained any references or escapes.
+func expandValue(value any) (result any, changed bool, err error) {
+	switch value := value.(type) {
+	case string:
+		return expandString(value)
+	case []any:
+		expanded := make([]any, len(value))
+		for i, item := range value {
+			itemChanged := false
+			if expanded[i], itemChanged, err = expandValue(item); err != nil {
+				return nil, false, err
+			}
+			changed = changed || itemChanged
+		}
+		return expanded, changed, nil
+	case map[string]any:
+		expanded := make(map[string]any, len(value))
+		for k, item := range value {
+			itemChanged := false
+			if expanded[k], itemChanged, err = expandValue(item); err != nil {
+				return nil, false, err
+			}
+			changed = changed || itemChanged
+		}
+		return expanded, changed, nil
+	default:
+		return value, false, nil
+	}
+}
+
+// expandString replaces ${VAR} references in s with the values of the env variables.
+//
+// Only the braced form is expanded, so other dollar signs (e.g., "$$LITERAL" or "pa$word") are left as is.
+// "$${" escapes the reference: "$${VAR}" results in the literal "${VAR}".
+// Referencing an unset variable is an error, a variable, set to an empty string, expands to it.
+func expandString(s string) (result string, changed bool, err error) {
+	if !strings.Contains(s, "${") {
+		return s, false, nil
+	}
+
+	var sb strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			sb.WriteString(s)
+			return sb.String(), true, nil
+		}
+
+		if i > 0 && s[i-1] == '$' {
+			// Escaped: dropping one of the dollar signs, keeping the reference literal.
+			sb.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", false, fmt.Errorf("unterminated ${ reference")
+		}
+		name := s[i+2 : i+end]
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			return "", false, fmt.Errorf("env variable %q is not set", name)
+		}
+		sb.WriteString(s[:i] + value)
+		s = s[i+end+1:]
+	}
+}
@@ -0,0 +1,68 @@
+package configkit
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// readFile reads the config file at name from the file system of the Loader (see WithFS).
+func (l *Loader) readFile(name string) ([]byte, error) {
+	if l.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(l.fsys, fsPath(name))
+}
+
+// stat returns the info of the config file at name in the file system of the Loader (see WithFS).
+func (l *Loader) stat(name string) (fs.FileInfo, error) {
+	if l.fsys == nil {
+		return os.Stat(name)
+	}
+	return fs.Stat(l.fsys, fsPath(name))
+}
+
+// readDir returns the entries of the config dir in the file system of the Loader (see WithFS), sorted by name.
+func (l *Loader) readDir(dir string) ([]fs.DirEntry, error) {
+	if l.fsys == nil {
+		return os.ReadDir(dir)
+	}
+	return fs.ReadDir(l.fsys, fsPath(dir))
+}
+
+// checkFile returns an error if the config file at name exceeds WithMaxConfigSize, or is accessible by others
+// (any of the 0o007 bits set) with WithSecureFilePermissions. Errors of stat (e.g., not exist) are returned as is.
+// The permissions are not checked on Windows, where the permission bits don't reflect the actual access rights.
+func (l *Loader) checkFile(name string) error {
+	checkPerms := l.securePerms && runtime.GOOS != "windows"
+	if !checkPerms && l.maxConfigSize <= 0 {
+		return nil
+	}
+	info, err := l.stat(name)
+	if err != nil {
+		return err
+	}
+	if l.maxConfigSize > 0 && info.Size() > l.maxConfigSize {
+		return fmt.Errorf("config file %q is %d bytes, exceeding the limit of %d bytes", name, info.Size(), l.maxConfigSize)
+	}
+	if perm := info.Mode().Perm(); checkPerms && perm&0o007 != 0 {
+		return fmt.Errorf("config file %q permissions %04o are too open - it must not be accessible by others (e.g., 0600 or 0640)",
+			name, perm)
+	}
+	return nil
+}
+
+// fsPath converts name to the path within fs.FS: slash-separated and cleaned, with the leading slash trimmed
+// (e.g., /etc/myapp/config.yaml → etc/myapp/config.yaml). Paths, escaping the root (e.g., ../config.yaml),
+// remain invalid and are rejected by fs.FS.
+func fsPath(name string) string {
+	p := strings.TrimLeft(path.Clean(filepath.ToSlash(name)), "/")
+	if p == "" {
+		return "."
+	}
+	return p
+}
@@ -0,0 +1,79 @@
+package configkit
+
+import (
+	"strings"
+
+	"github.com/hashicorp/hcl"
+	"github.com/spf13/viper"
+)
+
+// decoderRegistry extends the viper's default decoders with HCL, which viper doesn't decode out of the box.
+// The decoded keys are transformed by keyTransform, if set (see WithKeyTransform).
+type decoderRegistry struct {
+	viper.DecoderRegistry
+	keyTransform func(string) string
+}
+
+// newDecoderRegistry returns the decoder registry, used by all viper instances of the Loader.
+func newDecoderRegistry(keyTransform func(string) string) decoderRegistry {
+	return decoderRegistry{DecoderRegistry: viper.NewCodecRegistry(), keyTransform: keyTransform}
+}
+
+// Decoder returns the decoder for the format. Format is case-insensitive.
+func (r decoderRegistry) Decoder(format string) (viper.Decoder, error) {
+	var decoder viper.Decoder
+	switch strings.ToLower(format) {
+	case "hcl", "tfvars":
+		decoder = hclDecoder{}
+	default:
+		var err error
+		if decoder, err = r.DecoderRegistry.Decoder(format); err != nil {
+			return nil, err
+		}
+	}
+	if r.keyTransform != nil {
+		decoder = keyTransformDecoder{Decoder: decoder, transform: r.keyTransform}
+	}
+	return decoder, nil
+}
+
+// hclDecoder decodes HCL (v1) configs.
+type hclDecoder struct{}
+
+// Decode decodes HCL data into v.
+//
+// HCL decodes blocks (e.g., `db { host = "localhost" }`) as lists of maps, since a block may be repeated.
+// Such lists are merged into a single map, so blocks map to nested keys (db.host) and structs.
+// Note that HCL decodes lists of objects (e.g., `items = [{ a = 1 }]`) the same way, so they are merged as well.
+func (hclDecoder) Decode(data []byte, v map[string]any) error {
+	var raw map[string]any
+	if err := hcl.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		v[key] = mergeHCLBlocks(value)
+	}
+	return nil
+}
+
+// mergeHCLBlocks recursively merges the lists of maps (decoded HCL blocks) into single maps.
+// Later blocks override the keys of the earlier ones. Other values are returned as is.
+func mergeHCLBlocks(value any) any {
+	switch value := value.(type) {
+	case []map[string]any:
+		merged := make(map[string]any)
+		for _, block := range value {
+			for key, nested := range block {
+				merged[key] = mergeHCLBlocks(nested)
+			}
+		}
+		return merged
+	case map[string]any:
+		for key, nested := range value {
+			value[key] = mergeHCLBlocks(nested)
+		}
+		return value
+	default:
+		return value
+	}
+}
@@ -0,0 +1,308 @@
+package configkit
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// keyFormat defines how config keys are derived from struct fields.
+type keyFormat struct {
+	delim string // Separates nested keys.
+	tag   string // Struct tag with the key names (e.g., mapstructure).
+}
+
+// structField is the reflective metadata of an exported struct field, mapped to a config key.
+type structField struct {
+	field  reflect.StructField
+	index  int          // Index of the field in its struct.
+	name   string       // Config key name of the field (without the parent keys).
+	squash bool         // The field is squashed into its parent.
+	kind   fieldKind    // How the field is walked.
+	elem   reflect.Type // Field type with pointers dereferenced.
+}
+
+// fieldKind defines how a struct field is walked.
+type fieldKind int
+
+const (
+	fieldLeaf      fieldKind = iota // A single config value.
+	fieldStruct                     // A nested struct (or a pointer to it), walked recursively.
+	fieldStructMap                  // A map[string]Struct, which entries are walked by value.
+)
+
+// structKey is a leaf config key of a struct type along with the corresponding field.
+type structKey struct {
+	key   string
+	field reflect.StructField
+}
+
+// fieldsCacheKey identifies the cached fields of a struct type.
+type fieldsCacheKey struct {
+	t   reflect.Type
+	tag string
+}
+
+// keysCacheKey identifies the cached leaf keys of a struct type.
+type keysCacheKey struct {
+	t  reflect.Type
+	kf keyFormat
+}
+
+var (
+	// fieldsCache holds the []structField of struct types, so the tags are parsed once per type.
+	fieldsCache sync.Map // fieldsCacheKey → []structField
+
+	// keysCache holds the []structKey of struct types, so each type is walked once.
+	keysCache sync.Map // keysCacheKey → []structKey
+)
+
+// structFields returns the metadata of the exported fields of the struct type t, not skipped with the "-" tag name.
+// The result is computed once per type and tag, and must not be modified.
+func structFields(t reflect.Type, tag string) []structField {
+	cacheKey := fieldsCacheKey{t: t, tag: tag}
+	if fields, ok := fieldsCache.Load(cacheKey); ok {
+		return fields.([]structField)
+	}
+
+	fields := make([]structField, 0, t.NumField())
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, squash := fieldKey(field, tag)
+		if name == "-" {
+			continue
+		}
+
+		ft := field.Type
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+
+		kind := fieldLeaf
+		switch {
+		case ft.Kind() == reflect.Struct && !isLeafStruct(ft):
+			kind = fieldStruct
+		case isStructMap(ft):
+			kind = fieldStructMap
+		}
+		fields = append(fields, structField{field: field, index: i, name: name, squash: squash, kind: kind, elem: ft})
+	}
+
+	actual, _ := fieldsCache.LoadOrStore(cacheKey, fields)
+	return actual.([]structField)
+}
+
+// structKeys returns the config keys, expected by the struct type t (dotted paths of the tagged names).
+//
+// Nested structs (and pointers to them) are walked recursively, squashed structs are flattened.
+// Other fields (including maps and slices) are leaves. Self-referential types are walked once per path.
+func structKeys(t reflect.Type, kf keyFormat) []string {
+	var keys []string
+	walkStructKeys(t, "", kf, func(key string, _ reflect.StructField) {
+		keys = append(keys, key)
+	})
+	return keys
+}
+
+// walkStructKeys calls fn for each leaf config key of the struct type t, prefixed with prefix,
+// along with the corresponding struct field. The keys are computed once per type and key format.
+func walkStructKeys(t reflect.Type, prefix string, kf keyFormat, fn func(key string, field reflect.StructField)) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+
+	cacheKey := keysCacheKey{t: t, kf: kf}
+	keys, ok := keysCache.Load(cacheKey)
+	if !ok {
+		var collected []structKey
+		collectStructKeys(t, "", kf, map[reflect.Type]bool{}, &collected)
+		keys, _ = keysCache.LoadOrStore(cacheKey, collected)
+	}
+
+	for _, sk := range keys.([]structKey) {
+		fn(prefix+sk.key, sk.field)
+	}
+}
+
+// collectStructKeys appends the leaf config keys of the struct type t, prefixed with prefix, to keys.
+// visited holds the struct types on the current path to break cycles.
+func collectStructKeys(t reflect.Type, prefix string, kf keyFormat, visited map[reflect.Type]bool, keys *[]structKey) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for _, sf := range structFields(t, kf.tag) {
+		switch {
+		case sf.squash && sf.elem.Kind() == reflect.Struct:
+			collectStructKeys(sf.elem, prefix, kf, visited, keys)
+		case sf.kind == fieldStruct:
+			collectStructKeys(sf.elem, prefix+sf.name+kf.delim, kf, visited, keys)
+		default:
+			*keys = append(*keys, structKey{key: prefix + sf.name, field: sf.field})
+		}
+	}
+}
+
+// mapKeyPlaceholder stands for the entry names of map fields in the keys of ConfigKeys (e.g., labels.<key>).
+const mapKeyPlaceholder = "<key>"
+
+// ConfigKeys returns the config keys, expected by cfg (a struct or a pointer to it), in the field order:
+// the dotted paths of the tagged names (see WithTagName and WithKeyDelimiter), e.g. db.pool_size.
+// It needs no loading, so it suits generating docs or shell completions.
+//
+// Nested structs are walked recursively, squashed structs are flattened. Map entry names are shown
+// as <key>: labels.<key> for a map[string]string field, upstreams.<key>.url for a map[string]Upstream one.
+// Slices (of structs too) are single keys, as they are set as a whole.
+// Self-referential types are walked once per path. Returns nil if cfg is not a struct.
+func (l *Loader) ConfigKeys(cfg any) []string {
+	t := reflect.TypeOf(cfg)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var keys []string
+	collectConfigKeys(t, "", l.keyFormat(), map[reflect.Type]bool{}, &keys)
+	return keys
+}
+
+// collectConfigKeys appends the config keys of the struct type t, prefixed with prefix, to keys (see ConfigKeys).
+// visited holds the struct types on the current path to break cycles.
+func collectConfigKeys(t reflect.Type, prefix string, kf keyFormat, visited map[reflect.Type]bool, keys *[]string) {
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	for _, sf := range structFields(t, kf.tag) {
+		switch {
+		case sf.squash && sf.elem.Kind() == reflect.Struct:
+			collectConfigKeys(sf.elem, prefix, kf, visited, keys)
+		case sf.kind == fieldStruct:
+			collectConfigKeys(sf.elem, prefix+sf.name+kf.delim, kf, visited, keys)
+		case sf.kind == fieldStructMap:
+			elem, _ := structMapElem(sf.elem)
+			collectConfigKeys(elem, prefix+sf.name+kf.delim+mapKeyPlaceholder+kf.delim, kf, visited, keys)
+		case sf.elem.Kind() == reflect.Map:
+			*keys = append(*keys, prefix+sf.name+kf.delim+mapKeyPlaceholder)
+		default:
+			*keys = append(*keys, prefix+sf.name)
+		}
+	}
+}
+
+// fieldKey returns the config key of the struct field (its tag name or lowercased field name),
+// and whether the field is squashed into its parent.
+func fieldKey(field reflect.StructField, tag string) (name string, squash bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get(tag), ",")
+	squash = slices.Contains(strings.Split(opts, ","), "squash")
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name, squash
+}
+
+// isLeafStruct reports whether the struct type is decoded as a single value (e.g. time.Time).
+func isLeafStruct(t reflect.Type) bool {
+	return t.PkgPath() == "time" && t.Name() == "Time"
+}
+
+// isStructMap reports whether t is a map with string keys and struct values (or pointers to them).
+func isStructMap(t reflect.Type) bool {
+	_, ok := structMapElem(t)
+	return ok
+}
+
+// walkStructValues calls fn for each leaf config key of the struct value v, prefixed with prefix,
+// along with the corresponding struct field and its value. Keys are built the same way walkStructKeys does.
+// Unlike walkStructKeys, the entries of map[string]Struct fields are walked too (e.g., upstreams.<name>.url),
+// in the order of their names. Nil pointers to nested structs are not walked.
+//
+// Like walkStructKeys, self-referential types are walked once per path: a nested struct (or map entry)
+// of a type, already being walked, is skipped. So the walk terminates even on cyclic values.
+func walkStructValues(
+	v reflect.Value,
+	prefix string,
+	kf keyFormat,
+	fn func(key string, field reflect.StructField, value reflect.Value),
+) {
+	walkValues(v, prefix, kf, map[reflect.Type]bool{}, fn)
+}
+
+// walkValues implements walkStructValues. visited holds the struct types on the current path to break cycles.
+func walkValues(
+	v reflect.Value,
+	prefix string,
+	kf keyFormat,
+	visited map[reflect.Type]bool,
+	fn func(key string, field reflect.StructField, value reflect.Value),
+) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || visited[v.Type()] {
+		return
+	}
+	visited[v.Type()] = true
+	defer delete(visited, v.Type())
+
+	for _, sf := range structFields(v.Type(), kf.tag) {
+		switch {
+		case sf.squash && sf.elem.Kind() == reflect.Struct:
+			walkValues(v.Field(sf.index), prefix, kf, visited, fn)
+		case sf.kind == fieldStruct:
+			walkValues(v.Field(sf.index), prefix+sf.name+kf.delim, kf, visited, fn)
+		case sf.kind == fieldStructMap:
+			walkMapValues(v.Field(sf.index), prefix+sf.name+kf.delim, kf, visited, fn)
+		default:
+			fn(prefix+sf.name, sf.field, v.Field(sf.index))
+		}
+	}
+}
+
+// walkMapValues calls walkValues for each entry of the map[string]Struct value m,
+// prefixing the keys with prefix and the entry name. Map entries can't be set in place,
+// so each entry is walked as a copy, stored back into m afterwards (if m is settable).
+func walkMapValues(
+	m reflect.Value,
+	prefix string,
+	kf keyFormat,
+	visited map[reflect.Type]bool,
+	fn func(key string, field reflect.StructField, value reflect.Value),
+) {
+	for m.Kind() == reflect.Ptr {
+		if m.IsNil() {
+			return
+		}
+		m = m.Elem()
+	}
+
+	names := m.MapKeys()
+	slices.SortFunc(names, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	for _, name := range names {
+		entry := reflect.New(m.Type().Elem()).Elem()
+		entry.Set(m.MapIndex(name))
+		walkValues(entry, prefix+name.String()+kf.delim, kf, visited, fn)
+		if m.CanSet() {
+			m.SetMapIndex(name, entry)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package configkit
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/spf13/viper"
+)
+
+// keyTransformDecoder transforms the keys, decoded by the underlying Decoder (see WithKeyTransform).
+type keyTransformDecoder struct {
+	viper.Decoder
+	transform func(string) string
+}
+
+// Decode decodes data into v, transforming its keys at all levels of nesting.
+func (d keyTransformDecoder) Decode(data []byte, v map[string]any) error {
+	raw := make(map[string]any)
+	if err := d.Decoder.Decode(data, raw); err != nil {
+		return err
+	}
+	for key, value := range raw {
+		v[d.transform(key)] = transformKeys(value, d.transform)
+	}
+	return nil
+}
+
+// transformMapKeys returns a copy of m with the keys transformed at all levels of nesting.
+// m is returned as is, if transform is nil.
+func transformMapKeys(m map[string]any, transform func(string) string) map[string]any {
+	if transform == nil {
+		return m
+	}
+	return transformKeys(m, transform).(map[string]any)
+}
+
+// transformKeys returns a copy of value with the keys of the nested maps (including the ones in lists) transformed.
+// Other values are returned as is.
+func transformKeys(value any, transform func(string) string) any {
+	switch value := value.(type) {
+	case map[string]any:
+		transformed := make(map[string]any, len(value))
+		for key, nested := range value {
+			transformed[transform(key)] = transformKeys(nested, transform)
+		}
+		return transformed
+	case []any:
+		transformed := make([]any, len(value))
+		for i, nested := range value {
+			transformed[i] = transformKeys(nested, transform)
+		}
+		return transformed
+	default:
+		return value
+	}
+}
+
+// SnakeCaseKey converts a camelCase or PascalCase key to snake_case (e.g., poolSize → pool_size,
+// maxIdleConns → max_idle_conns). Acronyms are kept together: apiURL → api_url, HTTPServer → http_server.
+// Keys in snake_case are returned as is. It is meant to be used with WithKeyTransform.
+func SnakeCaseKey(key string) string {
+	runes := []rune(key)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && nextLower {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
@@ -1,10 +1,20 @@
 package configkit
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
 	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
@@ -13,69 +23,506 @@ type Loader struct {
 	name, short, long string // Root command attributes.
 	configPath        string
 	envPrefix         string
+
+	checkConfig    bool              // Enables the --check-config flag.
+	checkOnly      bool              // Enables the --config-check-only flag.
+	printConfig    bool              // Enables the --print-config flag.
+	envKeyReplacer *strings.Replacer // Maps config keys to env variable names. Derived from keyDelimiter if nil.
+	keyDelimiter   string            // Separates nested keys.
+	tagName        string            // Struct tag with the config key names.
+	envDisabled    bool              // Ignores environment variables entirely.
+	overlays       []configOverlay   // Config files merged on top of the main one.
+	configDirs     []string          // Directories, which config files are merged on top of the main one.
+	embedded       *embeddedConfig   // Default config, merged under the main one.
+	viperOpts      []viper.Option    // Passed through to viper.NewWithOptions.
+	strictKeys     bool              // Config keys must map to struct fields.
+	weaklyTyped    bool              // Allows weakly typed input on decoding.
+
+	keyTransform func(string) string // Applied to the decoded config keys before viper lowercases them.
+
+	profilesEnabled bool     // Enables the --env flag.
+	profiles        []string // Allowed profiles. Any profile is allowed if empty.
+
+	validators      []func(cfg any) error // Run after a successful unmarshal.
+	detailedResults bool                  // Returns the specific stop reason instead of LoadResultStop.
+
+	remote  *remoteProvider // Remote config source, merged under the config files.
+	sources []Source        // Custom config sources, merged with the config files by priority.
+
+	commands   []*cobra.Command    // Subcommands of the root command.
+	commandsMu *sync.Mutex         // Serializes the loads with subcommands, as they are shared by the root commands.
+	snapshots  *sync.Map           // cfg → raw values of its config keys at the last load. Nil unless WithMergeReload.
+	flags      *loadedFlags        // Built-in flags of the last successful load, reused by the reloads.
+	envTags    *sync.Map           // Config key → name of its `env:"NAME"` tag, of the configs loaded so far.
+	runE       func(cfg any) error // Service entrypoint, run after a successful load.
+	preRun     []func() error      // Run before the config loading.
+	postRun    []func() error      // Run after a successful config loading.
+
+	helpExamples string // Examples section of --help.
+	envHelp      bool   // Lists env variables in --help.
+
+	allowNilWriter bool // Replaces a nil writer with io.Discard instead of failing.
+
+	versionFormats map[string]func(io.Writer) error // Version printers, selectable by --version-format.
+
+	configName        string   // Base name of the config file to search for.
+	configSearchPaths []string // Directories to search the config file in.
+	configOptional    bool     // Missing main config file is not an error.
+	xdgDefaults       bool     // Search the config file in the XDG-style directories.
+	trimSpace         bool     // Trim whitespace from the string values after unmarshal.
+	envPrefixFlag     bool     // Enables --env-prefix flag.
+	validateCommand   bool     // Enables `config validate` subcommand.
+	completionCommand bool     // Enables the `completion` subcommand of cobra.
+	envExpansion      bool     // Expands ${VAR} references in the string values.
+
+	deprecatedKeys map[string]string // Deprecated config keys, mapped to their replacements.
+	fileCache      *fileCache        // Parsed config files, shared by all loads. Nil if disabled.
+
+	caseInsensitiveEnv bool   // Matches env variable names case-insensitively.
+	configFormat       string // Format of the main config file, overriding its extension. Empty to infer.
+	fsys               fs.FS  // File system to read the config files from. Nil for the OS one.
+	explicitEnv        bool   // Binds env variables to the declared keys only, instead of AutomaticEnv.
+
+	configNotFound   func(path string) error // Called when the main config file is missing. Nil if not set.
+	saveOmitDefaults bool                    // Values.SaveConfig writes the explicitly set keys only.
+	fallbackPrefixes []string                // Env prefixes, checked in order if the main one yields nothing.
+	allowEmptyEnv    bool                    // Treats set, but empty env variables as set.
+	securePerms      bool                    // Refuses the config files, accessible by others.
+	strictEnv        bool                    // Env variables under the prefix must map to config keys.
+	client           *http.Client            // Fetches the configs by URL. Nil for the default one.
+	maxConfigSize    int64                   // Size limit of the config files in bytes. Zero for no limit.
+
+	envBindings []string // Keys, explicitly bound to env variables.
+
+	logger *slog.Logger // Diagnostics of the loading. Discarded if nil.
+
+	warnings   *[]string // Warnings of the current load, collected by LoadDetailed. Nil if not collected.
+	warnWriter io.Writer // Output of the warnings of the current load, if no logger is set. Nil to discard them.
+}
+
+// configOverlay is an additional config file, merged on top of the main config.
+type configOverlay struct {
+	path     string
+	optional bool // Missing file is not an error.
+}
+
+// remoteProvider is a remote key/value store, used as a config source.
+type remoteProvider struct {
+	provider, endpoint, path, format string
+}
+
+// embeddedConfig is a default config, baked into the binary.
+type embeddedConfig struct {
+	fsys         fs.FS
+	path, format string
 }
 
 // NewLoader returns a new viper loader.
 //
-//   - name: short name of the service, as well as the name of the root command.
+//   - name: short name of the service, as well as the name of the root command. Must be non-empty, without spaces.
 //   - short, long: short and long descriptions of the service for the root command.
 //   - configPath is the path to the configuration file. It will be overrided with a value,
 //     received via the --config flag. If the flag is not set, Loader will use the configPath.
 //   - envPrefix: prefix for environment variables (e.g., "APP" → APP_LOG_LEVEL).
-func NewLoader(name, short, long, configPath, envPrefix string) *Loader {
-	return &Loader{
-		configPath: configPath,
-		envPrefix:  envPrefix,
-		name:       name,
-		short:      short,
-		long:       long,
+//     Must consist of letters, digits and underscores, and not start with a digit. Empty for no prefix.
+//   - opts: optional settings (e.g., WithCheckConfig, WithEnvKeyReplacer).
+//
+// The name and the prefix are checked on load, so the invalid ones are reported by Load (and the like).
+func NewLoader(name, short, long, configPath, envPrefix string, opts ...Option) *Loader {
+	l := &Loader{
+		configPath:   configPath,
+		envPrefix:    envPrefix,
+		name:         name,
+		short:        short,
+		long:         long,
+		keyDelimiter: ".",
+		tagName:      "mapstructure",
+		weaklyTyped:  true,
+		commandsMu:   &sync.Mutex{},
+		flags:        &loadedFlags{},
+		envTags:      &sync.Map{},
 	}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(l)
+		}
+	}
+	return l
 }
 
 // Load loads configuration from a file and environment variables into cfg.
 //
 // Arguments:
-//   - cfg must be a pointer to a struct.
+//   - cfg must be a pointer to a struct. Nested pointer-to-struct fields are allocated only
+//     if the config provides values for them, otherwise they are left nil.
 //   - printVersion is called when --version is used.
 //     Package provides to helpers (JSONVersionPrinter and PlainVersionPrinter) for a quick setup.
 //   - writer is used for output (can be os.Stdout, buffer, etc. - any writer to handle printVersion).
 //
-// Note: While Loader is stateless and uses isolated viper instances,
-// calling Load() concurrently with CLI flag parsing is not recommended,
-// as underlying libraries (such as cobra) are not designed for concurrent use.
-// Use Load() sequentially during application startup.
+// Load is safe for concurrent use: each call parses its own copy of os.Args with its own cobra command
+// and viper instance. The subcommands (see AddCommand) are shared by all the calls, so the calls of a Loader
+// with subcommands are serialized. Note that cfg itself must not be shared by the concurrent calls.
 //
 // Returns:
-//   - LoadResultStop: if --help or --version was used (no error).
-//   - LoadResultContinue: if config was loaded successfully.
+//   - LoadResultStop: if --help, --version, --check-config or --print-config was used,
+//     or a subcommand was executed (no error).
+//     With WithDetailedResults, the specific reason (e.g. LoadResultVersion) is returned instead.
+//   - LoadResultContinue: if config was loaded successfully (and the WithRunE entrypoint returned nil, if set).
 //   - error: if there was a problem (e.g. config file not found).
 func (l *Loader) Load(cfg any, printVersion func(io.Writer) error, writer io.Writer) (LoadResult, error) {
+	_, result, err := l.LoadWithViper(cfg, printVersion, writer)
+	return result, err
+}
+
+// LoadArgs acts like Load, but parses args (without the program name) instead of os.Args,
+// leaving os.Args untouched. It makes the loading independent of the global state, e.g. in tests.
+//
+// Reload and WatchSignal reuse the built-in flags (e.g., --config, --set) of the last successful load,
+// so the args are not parsed again on reload.
+func (l *Loader) LoadArgs(args []string, cfg any, printVersion func(io.Writer) error, writer io.Writer) (LoadResult, error) {
+	_, result, err := l.loadWithViper(args, cfg, printVersion, writer)
+	return result, err
+}
+
+// LoadInto acts like Load, but allocates the config of type T itself and returns it,
+// so a misuse (e.g. passing a non-pointer) is caught at compile time.
+//
+// The config is returned along with LoadResultContinue only. On stop or error, it is nil.
+func LoadInto[T any](l *Loader, printVersion func(io.Writer) error, writer io.Writer) (*T, LoadResult, error) {
+	cfg := new(T)
+	result, err := l.Load(cfg, printVersion, writer)
+	if err != nil || result != LoadResultContinue {
+		return nil, result, err
+	}
+	return cfg, result, nil
+}
+
+// LoadWithViper acts like Load, but also returns the underlying viper instance
+// for ad-hoc lookups (v.Get, v.Sub, etc.) without re-reading the config.
+//
+// The instance reflects the fully merged state: config files, env variables and flags.
+// It is nil if an error occurred. On --help and --version the config is not read,
+// so the instance holds no config values.
+func (l *Loader) LoadWithViper(cfg any, printVersion func(io.Writer) error, writer io.Writer) (*viper.Viper, LoadResult, error) {
+	var args []string
+	if len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+	return l.loadWithViper(args, cfg, printVersion, writer)
+}
+
+// LoadIntoMap acts like Load, but returns the merged config as a nested map (viper's AllSettings)
+// instead of decoding it into a struct. It suits the generic tooling without a typed config
+// (e.g., pass-through proxies or debugging).
+//
+// Without a struct, only the keys of the config files, the embedded default, remote config, --set
+// and WithEnvBindings are known: env variables override these keys, but don't add new ones.
+// The struct-specific features don't apply: validators, WithStrictKeys, WithStrictEnv and the WithRunE entrypoint.
+// The built-in keys (e.g. "config") are not included.
+//
+// The map is returned along with LoadResultContinue only. On stop or error, it is nil.
+func (l *Loader) LoadIntoMap(printVersion func(io.Writer) error, writer io.Writer) (map[string]any, LoadResult, error) {
+	var args []string
+	if len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+	return l.loadIntoMap(args, printVersion, writer)
+}
+
+// loadIntoMap implements LoadIntoMap, parsing args (without the program name).
+func (l *Loader) loadIntoMap(args []string, printVersion func(io.Writer) error, writer io.Writer) (map[string]any, LoadResult, error) {
+	// Loading into an empty struct with a copy, not expecting any keys, so the Loader itself stays intact.
+	lc := *l
+	lc.strictKeys, lc.strictEnv = false, false
+	lc.validators, lc.runE = nil, nil
+	lc.snapshots = nil // Nothing to merge into.
+
+	v, result, err := lc.loadWithViper(args, &struct{}{}, printVersion, writer)
+	if err != nil || result != LoadResultContinue {
+		return nil, result, err
+	}
+
+	settings := v.AllSettings()
+	for _, key := range l.builtinKeys() {
+		delete(settings, key)
+	}
+	return settings, result, nil
+}
+
+// loadWithViper implements LoadWithViper, parsing args (without the program name).
+func (l *Loader) loadWithViper(
+	args []string,
+	cfg any,
+	printVersion func(io.Writer) error,
+	writer io.Writer,
+) (*viper.Viper, LoadResult, error) {
 	// Validate the input.
-	if reflect.ValueOf(cfg).Kind() != reflect.Ptr {
-		return LoadResultStop, fmt.Errorf("cfg must be a pointer to a struct - got %s", reflect.ValueOf(cfg).Kind().String())
+	if err := validateTarget(cfg); err != nil {
+		return nil, LoadResultStop, err
+	}
+	if err := validateName(l.name); err != nil {
+		return nil, LoadResultStop, err
+	}
+	if err := l.validateEnvPrefix(l.envPrefix); err != nil {
+		return nil, LoadResultStop, err
 	}
 	if printVersion == nil {
-		return LoadResultStop, fmt.Errorf("printVersion must be a function")
+		return nil, LoadResultStop, fmt.Errorf("printVersion must be a function")
 	}
 	if writer == nil {
-		return LoadResultStop, fmt.Errorf("writer must be a non-nil writer")
+		if !l.allowNilWriter {
+			return nil, LoadResultStop, fmt.Errorf("writer must be a non-nil writer")
+		}
+		writer = io.Discard
+	}
+
+	// Warnings of the load go to its writer. Setting it on a copy, so the Loader itself stays stateless.
+	lc := *l
+	lc.warnWriter = writer
+	l = &lc
+
+	// Each load builds its own root command and viper instance, but the subcommands are attached to all of them.
+	if len(l.commands) > 0 {
+		l.commandsMu.Lock()
+		defer l.commandsMu.Unlock()
 	}
 
-	v := viper.New()
+	v := l.newViper()
 
 	cmd, err := l.buildRootCommand(v, cfg, printVersion, writer)
 	if err != nil {
-		return LoadResultStop, fmt.Errorf("build root command: %w", err)
+		return nil, LoadResultStop, fmt.Errorf("build root command: %w", err)
+	}
+
+	// Setting args explicitly, as cobra falls back to os.Args only if they are nil.
+	cmd.SetArgs(append([]string{}, args...))
+	executed, err := cmd.ExecuteC()
+	if err != nil {
+		return nil, LoadResultStop, fmt.Errorf("execute root command: %w", err)
+	}
+
+	// If --help, --version, --check-config (or --config-check-only) or --print-config was triggered,
+	// or a subcommand was executed, stop gracefully.
+	var result LoadResult
+	switch {
+	case executed.Flags().Changed("help"):
+		result = LoadResultHelp
+	case executed != cmd:
+		result = LoadResultCommand
+	case cmd.Flags().Changed("version"):
+		result = LoadResultVersion
+	case cmd.Flags().Changed("check-config"), cmd.Flags().Changed(checkOnlyFlag):
+		result = LoadResultCheckConfig
+	case cmd.Flags().Changed("print-config"):
+		result = LoadResultPrintConfig
+	default:
+		return v, LoadResultContinue, nil
+	}
+	if !l.detailedResults {
+		result = LoadResultStop
+	}
+
+	return v, result, nil
+}
+
+// AddCommand registers subcommands (e.g. migrate, seed) of the root command.
+//
+// The config is loaded before any subcommand runs, so the subcommands may use the cfg passed to Load.
+// When a subcommand is executed, the root command does not run and Load returns LoadResultStop
+// (LoadResultCommand with WithDetailedResults).
+//
+// Subcommands must not define flags, colliding by name or shorthand with the built-in persistent flags
+// (--config/-c, --env/-e, --env-prefix): Load returns an error in this case.
+//
+// Note: if a subcommand defines its own PersistentPreRun(E), cobra runs it instead of the config loading.
+func (l *Loader) AddCommand(cmds ...*cobra.Command) {
+	l.commands = append(l.commands, cmds...)
+}
+
+// LoadFromReader loads configuration from r and environment variables into cfg.
+//
+// Unlike Load, it bypasses CLI processing entirely: no flags are parsed and no files are read.
+// It is handy for embedded configs, secrets managers and tests.
+//
+// Arguments:
+//   - cfg must be a pointer to a struct.
+//   - r is the source of the config. An empty reader results in an empty config.
+//   - format is the config format (e.g., "yaml", "json", "toml", "jsonc").
+//
+// Returns:
+//   - LoadResultContinue: if config was loaded successfully.
+//   - error: if there was a problem (e.g. unknown format or malformed config).
+func (l *Loader) LoadFromReader(cfg any, r io.Reader, format string) (LoadResult, error) {
+	// Validate the input.
+	if err := validateTarget(cfg); err != nil {
+		return LoadResultStop, err
+	}
+	if err := l.validateEnvPrefix(l.envPrefix); err != nil {
+		return LoadResultStop, err
+	}
+	if r == nil {
+		return LoadResultStop, fmt.Errorf("reader must be a non-nil reader")
+	}
+	if !isSupportedFormat(format) {
+		return LoadResultStop, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	v := l.newViper()
+	if format == formatJSONC {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return LoadResultStop, fmt.Errorf("read config: %w", err)
+		}
+		r, format = bytes.NewReader(stripJSONComments(data)), "json"
+	}
+	v.SetConfigType(format)
+	if err := v.ReadConfig(r); err != nil {
+		return LoadResultStop, fmt.Errorf("read config: %w", err)
+	}
+
+	if err := l.unmarshal(v, cfg); err != nil {
+		return LoadResultStop, fmt.Errorf("unmarshal config: %w", err)
+	}
+
+	if err := l.validate(cfg); err != nil {
+		return LoadResultStop, err
+	}
+
+	return LoadResultContinue, nil
+}
+
+// LoadFromMap loads configuration from m (nested maps for nested keys) and environment variables into cfg.
+//
+// Like LoadFromReader, it bypasses CLI processing and files. It is handy for tests, as no file has to be written.
+// Defaults, decode hooks and validators apply the same way Load does.
+//
+// Returns:
+//   - LoadResultContinue: if config was loaded successfully.
+//   - error: if there was a problem (e.g. a value can't be decoded or validation failed).
+func (l *Loader) LoadFromMap(cfg any, m map[string]any) (LoadResult, error) {
+	if err := validateTarget(cfg); err != nil {
+		return LoadResultStop, err
+	}
+	if err := l.validateEnvPrefix(l.envPrefix); err != nil {
+		return LoadResultStop, err
+	}
+
+	v := l.newViper()
+	if err := v.MergeConfigMap(transformMapKeys(m, l.keyTransform)); err != nil {
+		return LoadResultStop, fmt.Errorf("read config: %w", err)
 	}
 
-	if err := cmd.Execute(); err != nil {
-		return LoadResultStop, fmt.Errorf("execute root command: %w", err)
+	if err := l.unmarshal(v, cfg); err != nil {
+		return LoadResultStop, fmt.Errorf("unmarshal config: %w", err)
 	}
 
-	// If --help or --version was triggered, stop gracefully.
-	if cmd.Flags().Changed("help") || cmd.Flags().Changed("version") {
-		return LoadResultStop, nil
+	if err := l.validate(cfg); err != nil {
+		return LoadResultStop, err
 	}
 
 	return LoadResultContinue, nil
 }
+
+// newViper returns a new isolated viper instance, set up according to the Loader options.
+func (l *Loader) newViper() *viper.Viper {
+	v := viper.NewWithOptions(l.viperOptions()...)
+	if !l.envDisabled {
+		v.SetEnvKeyReplacer(l.envReplacer())
+		v.AllowEmptyEnv(l.allowEmptyEnv)
+		if !l.explicitEnv {
+			v.AutomaticEnv()
+		}
+		l.setEnvPrefix(v, l.envPrefix)
+	}
+	return v
+}
+
+// viperOptions returns the options of the viper instances, created by the Loader (including the temporary ones).
+func (l *Loader) viperOptions() []viper.Option {
+	return append([]viper.Option{viper.KeyDelimiter(l.keyDelimiter), viper.WithDecoderRegistry(newDecoderRegistry(l.keyTransform))}, l.viperOpts...)
+}
+
+// setEnvPrefix sets the env prefix of v and (re)binds the explicit env bindings, which names depend on it.
+func (l *Loader) setEnvPrefix(v *viper.Viper, prefix string) {
+	v.SetEnvPrefix(prefix)
+	for _, key := range l.envBindings {
+		_ = v.BindEnv(key) // Never fails with a key given.
+	}
+	if l.explicitEnv {
+		// No AutomaticEnv to look the built-in keys (e.g., PREFIX_CONFIG) up.
+		for _, key := range l.builtinKeys() {
+			_ = v.BindEnv(key) // Never fails with a key given.
+		}
+	}
+	l.bindEnvFold(v, l.builtinKeys())
+}
+
+// keyFormat returns the format of the config keys, derived from the struct fields.
+func (l *Loader) keyFormat() keyFormat {
+	return keyFormat{delim: l.keyDelimiter, tag: l.tagName}
+}
+
+// envReplacer returns the replacer, mapping config keys to env variable names.
+func (l *Loader) envReplacer() *strings.Replacer {
+	if l.envKeyReplacer != nil {
+		return l.envKeyReplacer
+	}
+	return strings.NewReplacer(l.keyDelimiter, "_")
+}
+
+// envVarName returns the name of the env variable for the config key, the same way viper v derives it.
+// The env prefix is taken from v, as it may be overridden by --env-prefix.
+func (l *Loader) envVarName(v *viper.Viper, key string) string {
+	return l.prefixedEnvName(v.GetEnvPrefix(), key)
+}
+
+// prefixedEnvName returns the name of the env variable for the config key with the given prefix.
+func (l *Loader) prefixedEnvName(prefix, key string) string {
+	name := key
+	if prefix != "" {
+		name = prefix + "_" + key
+	}
+	return l.envReplacer().Replace(strings.ToUpper(name))
+}
+
+// log returns the logger for the loading diagnostics.
+func (l *Loader) log() *slog.Logger {
+	if l.logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return l.logger
+}
+
+// validateTarget checks that cfg is suitable for unmarshaling: a non-nil pointer to a struct.
+func validateTarget(cfg any) error {
+	rv := reflect.ValueOf(cfg)
+	switch {
+	case rv.Kind() != reflect.Ptr:
+		return fmt.Errorf("cfg must be a pointer to a struct - got %s", rv.Kind().String())
+	case rv.IsNil():
+		return fmt.Errorf("cfg pointer is nil - pass a pointer to an allocated struct (e.g., &Config{})")
+	case rv.Elem().Kind() != reflect.Struct:
+		return fmt.Errorf("cfg must be a pointer to a struct - got %s", rv.Type().String())
+	}
+	return nil
+}
+
+// envPrefixRe matches valid env prefixes: identifiers of letters, digits and underscores.
+var envPrefixRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateName checks that name is usable as the root command name: non-empty and without whitespace.
+func validateName(name string) error {
+	if name == "" || strings.ContainsFunc(name, unicode.IsSpace) {
+		return fmt.Errorf("name must be a non-empty command name without spaces - got %q", name)
+	}
+	return nil
+}
+
+// validateEnvPrefix checks that the env prefix is either empty (no prefix) or a valid identifier (e.g., MYAPP).
+// Prefixes aren't checked if env variables are disabled.
+func (l *Loader) validateEnvPrefix(prefix string) error {
+	if l.envDisabled || prefix == "" || envPrefixRe.MatchString(prefix) {
+		return nil
+	}
+	return fmt.Errorf("env prefix must contain only letters, digits and underscores, and not start with a digit - got %q", prefix)
+}
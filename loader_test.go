@@ -2,13 +2,30 @@ package configkit
 
 import (
 	"bytes"
+	"embed"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"testing/fstest"
+	"time"
 
+	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/suite"
 	"gopkg.in/yaml.v3"
@@ -16,6 +33,9 @@ import (
 
 var errSomeError = fmt.Errorf("some error")
 
+//go:embed testdata/embedded
+var embeddedFS embed.FS
+
 type LoaderSuite struct {
 	suite.Suite
 }
@@ -38,6 +58,7 @@ func (s *LoaderSuite) TestLoad_ArgsValidation() {
 		args          []string
 		expected      LoadResult
 		expectedError error
+		errorContains string
 	}{
 		{
 			name:          "nonpointer config",
@@ -48,6 +69,36 @@ func (s *LoaderSuite) TestLoad_ArgsValidation() {
 			expected:      LoadResultStop,
 			expectedError: errSomeError,
 		},
+		{
+			name:          "nil config",
+			cfg:           nil,
+			printVersion:  PlainVersionPrinter("v1.0.0"),
+			writer:        &bytes.Buffer{},
+			args:          []string{},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+			errorContains: "cfg must be a pointer to a struct",
+		},
+		{
+			name:          "nil pointer config",
+			cfg:           (*struct{ Port int })(nil),
+			printVersion:  PlainVersionPrinter("v1.0.0"),
+			writer:        &bytes.Buffer{},
+			args:          []string{},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+			errorContains: "cfg pointer is nil",
+		},
+		{
+			name:          "pointer to non-struct config",
+			cfg:           new(int),
+			printVersion:  PlainVersionPrinter("v1.0.0"),
+			writer:        &bytes.Buffer{},
+			args:          []string{},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+			errorContains: "got *int",
+		},
 		{
 			name:          "nil printVersion",
 			cfg:           &struct{}{},
@@ -72,11 +123,13 @@ func (s *LoaderSuite) TestLoad_ArgsValidation() {
 		s.Run(tC.name, func() {
 			s.T().Parallel()
 			loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP")
-			os.Args = append([]string{"testapp"}, tC.args...)
-			result, err := loader.Load(tC.cfg, tC.printVersion, tC.writer)
+			result, err := loader.LoadArgs(tC.args, tC.cfg, tC.printVersion, tC.writer)
 
 			if tC.expectedError != nil {
 				s.Require().Error(err, "expected error, got nil")
+				if tC.errorContains != "" {
+					s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				}
 			} else {
 				s.Require().NoError(err, "expected nil, got error")
 			}
@@ -85,6 +138,88 @@ func (s *LoaderSuite) TestLoad_ArgsValidation() {
 	}
 }
 
+func (s *LoaderSuite) TestLoad_LoaderSettingsValidation() {
+	testCases := []struct {
+		name          string
+		loaderName    string
+		envPrefix     string
+		args          []string
+		opts          []Option
+		errorContains string
+	}{
+		{
+			name:       "valid settings",
+			loaderName: "testapp",
+			envPrefix:  "TEST_APP2",
+		},
+		{
+			name:       "empty prefix",
+			loaderName: "testapp",
+		},
+		{
+			name:          "empty name",
+			envPrefix:     "TESTAPP",
+			errorContains: `name must be a non-empty command name without spaces - got ""`,
+		},
+		{
+			name:          "name with spaces",
+			loaderName:    "test app",
+			envPrefix:     "TESTAPP",
+			errorContains: `name must be a non-empty command name without spaces - got "test app"`,
+		},
+		{
+			name:          "prefix with spaces",
+			loaderName:    "testapp",
+			envPrefix:     "TEST APP",
+			errorContains: `env prefix must contain only letters, digits and underscores, and not start with a digit - got "TEST APP"`,
+		},
+		{
+			name:          "prefix starting with digit",
+			loaderName:    "testapp",
+			envPrefix:     "1APP",
+			errorContains: `got "1APP"`,
+		},
+		{
+			name:       "prefix ignored without env",
+			loaderName: "testapp",
+			envPrefix:  "TEST APP",
+			opts:       []Option{WithoutEnv()},
+		},
+		{
+			name:          "invalid prefix flag",
+			loaderName:    "testapp",
+			envPrefix:     "TESTAPP",
+			args:          []string{"--env-prefix", "OTHER-APP"},
+			opts:          []Option{WithEnvPrefixFlag()},
+			errorContains: `got "OTHER-APP"`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			loader := NewLoader(tC.loaderName, "Test App", "", configPath, tC.envPrefix, tC.opts...)
+			result, err := loader.LoadArgs(tC.args, &struct{ Port int }{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+		})
+	}
+
+	s.Run("prefix checked without CLI", func() {
+		loader := NewLoader("testapp", "Test App", "", "", "TEST APP")
+		_, err := loader.LoadFromMap(&struct{ Port int }{}, map[string]any{"port": 8080})
+		s.Require().ErrorContains(err, `got "TEST APP"`, "unexpected LoadFromMap error")
+		_, err = loader.LoadFromReader(&struct{ Port int }{}, strings.NewReader("port: 8080\n"), "yaml")
+		s.Require().ErrorContains(err, `got "TEST APP"`, "unexpected LoadFromReader error")
+	})
+}
+
 func (s *LoaderSuite) TestLoad_FlagsProcessing() {
 	testCases := []struct {
 		name          string
@@ -167,8 +302,7 @@ func (s *LoaderSuite) TestLoad_FlagsProcessing() {
 		s.Run(tC.name, func() {
 			s.T().Parallel()
 			loader := NewLoader("testapp", "Test App", "", tC.configPath, "TESTAPP")
-			os.Args = append([]string{"testapp"}, tC.args...)
-			result, err := loader.Load(tC.cfg, tC.printVersion, tC.writer)
+			result, err := loader.LoadArgs(tC.args, tC.cfg, tC.printVersion, tC.writer)
 
 			if tC.expectedError != nil {
 				s.Require().Error(err, "expected error, got nil")
@@ -223,8 +357,7 @@ func (s *LoaderSuite) TestLoad_IncorrectConfig() {
 		s.Run(tC.name, func() {
 			s.T().Parallel()
 			loader := NewLoader("testapp", "Test App", "", tC.configPath, "TESTAPP")
-			os.Args = append([]string{"testapp"}, tC.args...)
-			result, err := loader.Load(tC.cfg, tC.printVersion, tC.writer)
+			result, err := loader.LoadArgs(tC.args, tC.cfg, tC.printVersion, tC.writer)
 
 			if tC.expectedError != nil {
 				s.Require().Error(err, "expected error, got nil")
@@ -369,9 +502,8 @@ func (s *LoaderSuite) TestLoad_ValidCases() {
 				tC.args[1] = actualConfigPath
 			}
 
-			os.Args = append([]string{"testapp"}, tC.args...)
 			cfg := &testConfig{}
-			result, err := loader.Load(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
 
 			if tC.expectedError != nil {
 				s.Require().Error(err, "expected error, got nil")
@@ -401,9 +533,8 @@ func (s *LoaderSuite) TestLoad_SequentialLoads() {
 		defer os.Remove(configPath1)
 
 		loader1 := NewLoader("testapp", "Test App", "", configPath1, "TESTAPP")
-		os.Args = []string{"testapp"}
 		cfg1 := &testConfig{}
-		result1, err1 := loader1.Load(cfg1, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		result1, err1 := loader1.LoadArgs(nil, cfg1, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
 		s.Require().NoError(err1, "expected nil, got error")
 		s.Require().Equal(LoadResultContinue, result1, "unexpected load result: got %v, want %v", result1, LoadResultContinue)
 		s.Require().Equal(
@@ -422,9 +553,8 @@ func (s *LoaderSuite) TestLoad_SequentialLoads() {
 		defer os.Remove(configPath2)
 
 		loader2 := NewLoader("testapp", "Test App", "", configPath2, "TESTAPP")
-		os.Args = []string{"testapp"}
 		cfg2 := &testConfig{}
-		result2, err2 := loader2.Load(cfg2, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		result2, err2 := loader2.LoadArgs(nil, cfg2, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
 		s.Require().NoError(err2, "expected nil, got error")
 		s.Require().Equal(LoadResultContinue, result2, "unexpected load result: got %v, want %v", result2, LoadResultContinue)
 		s.Require().Equal(
@@ -434,3 +564,7071 @@ func (s *LoaderSuite) TestLoad_SequentialLoads() {
 		)
 	})
 }
+
+// writeConfigFile writes data into a file with the given name inside a temporary directory.
+// Returns the path to the file. The directory is removed after the test.
+func (s *LoaderSuite) writeConfigFile(name string, data []byte) string {
+	s.T().Helper()
+	path := filepath.Join(s.T().TempDir(), name)
+	s.Require().NoError(os.WriteFile(path, data, 0o600), "write config file")
+	return path
+}
+
+func (s *LoaderSuite) TestLoad_CheckConfig() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		content       string
+		opts          []Option
+		args          []string
+		expected      LoadResult
+		expectedError error
+	}{
+		{
+			name:     "valid config",
+			content:  "log_level: info\nport: 8080\n",
+			opts:     []Option{WithCheckConfig()},
+			args:     []string{"--check-config"},
+			expected: LoadResultStop,
+		},
+		{
+			name:          "invalid config",
+			content:       "log_level: info\nport: not-a-number\n",
+			opts:          []Option{WithCheckConfig()},
+			args:          []string{"--check-config"},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+		{
+			name:     "option enabled, flag unset",
+			content:  "log_level: info\nport: 8080\n",
+			opts:     []Option{WithCheckConfig()},
+			args:     []string{},
+			expected: LoadResultContinue,
+		},
+		{
+			name:          "flag without option",
+			content:       "log_level: info\nport: 8080\n",
+			args:          []string{"--check-config"},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvKeyReplacer() {
+	type testConfig struct {
+		DB struct {
+			PoolSize int `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		envVars  map[string]string
+		expected int
+	}{
+		{
+			name:     "default replacer",
+			envVars:  map[string]string{"TESTAPP_DB_POOL_SIZE": "20"},
+			expected: 20,
+		},
+		{
+			name:     "double underscore replacer",
+			opts:     []Option{WithEnvKeyReplacer(strings.NewReplacer(".", "__"))},
+			envVars:  map[string]string{"TESTAPP_DB__POOL_SIZE": "30"},
+			expected: 30,
+		},
+		{
+			name:     "double underscore replacer ignores default naming",
+			opts:     []Option{WithEnvKeyReplacer(strings.NewReplacer(".", "__"))},
+			envVars:  map[string]string{"TESTAPP_DB_POOL_SIZE": "20"},
+			expected: 10,
+		},
+		{
+			name:     "nil replacer keeps default",
+			opts:     []Option{WithEnvKeyReplacer(nil)},
+			envVars:  map[string]string{"TESTAPP_DB_POOL_SIZE": "20"},
+			expected: 20,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("db:\n  pool_size: 10\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expected, cfg.DB.PoolSize, "unexpected pool size: got %d, want %d", cfg.DB.PoolSize, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_WithoutEnv() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		envVars        map[string]string
+		expectedConfig testConfig
+	}{
+		{
+			name:           "env enabled by default",
+			envVars:        map[string]string{"TESTAPP_PORT": "7070"},
+			expectedConfig: testConfig{LogLevel: "info", Port: 7070},
+		},
+		{
+			name:           "env disabled",
+			opts:           []Option{WithoutEnv()},
+			envVars:        map[string]string{"TESTAPP_PORT": "7070", "TESTAPP_LOG_LEVEL": "debug"},
+			expectedConfig: testConfig{LogLevel: "info", Port: 8080},
+		},
+		{
+			name:           "env disabled ignores config path",
+			opts:           []Option{WithoutEnv()},
+			envVars:        map[string]string{"TESTAPP_CONFIG": "nonexistent.yaml"},
+			expectedConfig: testConfig{LogLevel: "info", Port: 8080},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("log_level: info\nport: 8080\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_AdditionalConfigFiles() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	baseContent := "log_level: info\ndb:\n  url: localhost:5432\n  pool_size: 10\n"
+
+	testCases := []struct {
+		name           string
+		overlays       map[string]string // File name -> content.
+		opts           func(dir string) []Option
+		expectedConfig testConfig
+		expectedError  error
+	}{
+		{
+			name:     "deep merge of nested maps",
+			overlays: map[string]string{"overrides.yaml": "db:\n  pool_size: 20\n"},
+			opts: func(dir string) []Option {
+				return []Option{WithAdditionalConfigFiles(filepath.Join(dir, "overrides.yaml"))}
+			},
+			expectedConfig: func() testConfig {
+				cfg := testConfig{LogLevel: "info"}
+				cfg.DB.URL = "localhost:5432"
+				cfg.DB.PoolSize = 20
+				return cfg
+			}(),
+		},
+		{
+			name: "later files override earlier ones",
+			overlays: map[string]string{
+				"first.yaml":  "log_level: warn\ndb:\n  url: first:5432\n",
+				"second.yaml": "log_level: debug\n",
+			},
+			opts: func(dir string) []Option {
+				return []Option{WithAdditionalConfigFiles(filepath.Join(dir, "first.yaml"), filepath.Join(dir, "second.yaml"))}
+			},
+			expectedConfig: func() testConfig {
+				cfg := testConfig{LogLevel: "debug"}
+				cfg.DB.URL = "first:5432"
+				cfg.DB.PoolSize = 10
+				return cfg
+			}(),
+		},
+		{
+			name: "missing optional file is skipped",
+			opts: func(dir string) []Option {
+				return []Option{WithOptionalConfigFiles(filepath.Join(dir, "missing.yaml"))}
+			},
+			expectedConfig: func() testConfig {
+				cfg := testConfig{LogLevel: "info"}
+				cfg.DB.URL = "localhost:5432"
+				cfg.DB.PoolSize = 10
+				return cfg
+			}(),
+		},
+		{
+			name: "missing required file",
+			opts: func(dir string) []Option {
+				return []Option{WithAdditionalConfigFiles(filepath.Join(dir, "missing.yaml"))}
+			},
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(baseContent))
+			dir := filepath.Dir(configPath)
+			for name, content := range tC.overlays {
+				s.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600), "write overlay file")
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts(dir)...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadFromReader() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name           string
+		reader         io.Reader
+		format         string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expected       LoadResult
+		expectedError  error
+	}{
+		{
+			name:           "yaml",
+			reader:         bytes.NewReader([]byte("log_level: info\nport: 8080\n")),
+			format:         "yaml",
+			expectedConfig: testConfig{LogLevel: "info", Port: 8080},
+			expected:       LoadResultContinue,
+		},
+		{
+			name:           "json",
+			reader:         bytes.NewReader([]byte(`{"log_level": "warn", "port": 9090}`)),
+			format:         "json",
+			expectedConfig: testConfig{LogLevel: "warn", Port: 9090},
+			expected:       LoadResultContinue,
+		},
+		{
+			name:           "env override",
+			reader:         bytes.NewReader([]byte("log_level: info\nport: 8080\n")),
+			format:         "yaml",
+			envVars:        map[string]string{"TESTAPP_PORT": "7070"},
+			expectedConfig: testConfig{LogLevel: "info", Port: 7070},
+			expected:       LoadResultContinue,
+		},
+		{
+			name:           "empty reader",
+			reader:         bytes.NewReader(nil),
+			format:         "yaml",
+			expectedConfig: testConfig{},
+			expected:       LoadResultContinue,
+		},
+		{
+			name:          "unknown format",
+			reader:        bytes.NewReader([]byte("log_level: info\n")),
+			format:        "xml",
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+		{
+			name:          "malformed config",
+			reader:        bytes.NewReader([]byte("invalid: yaml: content")),
+			format:        "yaml",
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+		{
+			name:          "nil reader",
+			format:        "yaml",
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", "", "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadFromReader(cfg, tC.reader, tC.format)
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+				s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EmbeddedDefault() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	newConfig := func(logLevel string, port int, url string, poolSize int) testConfig {
+		cfg := testConfig{LogLevel: logLevel, Port: port}
+		cfg.DB.URL = url
+		cfg.DB.PoolSize = poolSize
+		return cfg
+	}
+
+	testCases := []struct {
+		name           string
+		content        string // External config content. Empty means no external file.
+		embeddedPath   string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  error
+	}{
+		{
+			name:           "fallback to embedded",
+			embeddedPath:   "testdata/embedded/config.yaml",
+			expectedConfig: newConfig("info", 8080, "localhost:5432", 10),
+		},
+		{
+			name:           "external file overrides embedded",
+			content:        "port: 9090\ndb:\n  pool_size: 20\n",
+			embeddedPath:   "testdata/embedded/config.yaml",
+			expectedConfig: newConfig("info", 9090, "localhost:5432", 20),
+		},
+		{
+			name:           "env overrides embedded",
+			embeddedPath:   "testdata/embedded/config.yaml",
+			envVars:        map[string]string{"TESTAPP_LOG_LEVEL": "debug"},
+			expectedConfig: newConfig("debug", 8080, "localhost:5432", 10),
+		},
+		{
+			name:          "missing embedded path",
+			embeddedPath:  "testdata/embedded/missing.yaml",
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := filepath.Join(s.T().TempDir(), "config.yaml")
+			if tC.content != "" {
+				configPath = s.writeConfigFile("config.yaml", []byte(tC.content))
+			}
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+				WithEmbeddedDefault(embeddedFS, tC.embeddedPath, "yaml"))
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ViperOptions() {
+	type testConfig struct {
+		APIKey string            `mapstructure:"apiKey"`
+		Hosts  map[string]string `mapstructure:"hosts"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		expectedConfig testConfig
+		expectedError  error
+	}{
+		{
+			// "example.com" is split into nested keys, which can't be decoded into map[string]string.
+			name:          "default key delimiter",
+			expectedError: errSomeError,
+		},
+		{
+			name: "custom key delimiter passed through",
+			opts: []Option{WithViperOptions(viper.KeyDelimiter("::"))},
+			expectedConfig: testConfig{
+				APIKey: "secret", // Mixed-case key is matched case-insensitively.
+				Hosts:  map[string]string{"example.com": "10.0.0.1"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("apiKey: secret\nhosts:\n  example.com: 10.0.0.1\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+
+	s.Run("mixed-case key round-trip", func() {
+		// Keys are lowercased by viper on read and can't be preserved: the dump has apikey, the struct still decodes.
+		configPath := s.writeConfigFile("config.yaml", []byte("apiKey: secret\n"))
+		os.Args = []string{"testapp"}
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		values, _, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		dumpPath := filepath.Join(s.T().TempDir(), "dump.yaml")
+		s.Require().NoError(values.SaveConfig(dumpPath), "expected nil, got error")
+		dump, err := os.ReadFile(dumpPath)
+		s.Require().NoError(err, "read dump")
+		s.Require().Equal("apikey: secret\n", string(dump), "key must be written lowercased")
+
+		cfg := &testConfig{}
+		_, err = NewLoader("testapp", "Test App", "", dumpPath, "TESTAPP").
+			LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("secret", cfg.APIKey, "dumped key must still decode into the mixed-case tagged field")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_KeyDelimiter() {
+	type testConfig struct {
+		Hosts map[string]string `mapstructure:"hosts"`
+		DB    struct {
+			PoolSize int `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name             string
+		opts             []Option
+		envVars          map[string]string
+		expectedHosts    map[string]string
+		expectedPoolSize int
+	}{
+		{
+			name:             "dotted key unmarshals into map",
+			opts:             []Option{WithKeyDelimiter("::")},
+			expectedHosts:    map[string]string{"example.com": "10.0.0.1"},
+			expectedPoolSize: 10,
+		},
+		{
+			name:             "env replacer adapts to delimiter",
+			opts:             []Option{WithKeyDelimiter("::")},
+			envVars:          map[string]string{"TESTAPP_DB_POOL_SIZE": "20"},
+			expectedHosts:    map[string]string{"example.com": "10.0.0.1"},
+			expectedPoolSize: 20,
+		},
+		{
+			name: "custom env replacer wins",
+			opts: []Option{
+				WithKeyDelimiter("::"),
+				WithEnvKeyReplacer(strings.NewReplacer("::", "__")),
+			},
+			envVars:          map[string]string{"TESTAPP_DB__POOL_SIZE": "30"},
+			expectedHosts:    map[string]string{"example.com": "10.0.0.1"},
+			expectedPoolSize: 30,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("hosts:\n  example.com: 10.0.0.1\ndb:\n  pool_size: 10\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedHosts, cfg.Hosts, "unexpected hosts: got %v, want %v", cfg.Hosts, tC.expectedHosts)
+			s.Require().Equal(tC.expectedPoolSize, cfg.DB.PoolSize, "unexpected pool size: got %d, want %d", cfg.DB.PoolSize, tC.expectedPoolSize)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadWithViper() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	s.Run("merged state", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nextra:\n  name: svc\n  tags: [a, b]\n"))
+		s.T().Setenv("TESTAPP_PORT", "7070")
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		os.Args = []string{"testapp"}
+		cfg := &testConfig{}
+		v, result, err := loader.LoadWithViper(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().NotNil(v, "expected viper instance, got nil")
+		s.Require().Equal(7070, v.GetInt("port"), "env override must be reflected")
+		s.Require().Equal("svc", v.GetString("extra.name"), "unexpected arbitrary key value")
+		s.Require().Equal([]string{"a", "b"}, v.Sub("extra").GetStringSlice("tags"), "unexpected sub-tree value")
+		s.Require().Equal(configPath, v.ConfigFileUsed(), "unexpected config file used")
+	})
+
+	s.Run("error", func() {
+		loader := NewLoader("testapp", "Test App", "", "nonexistent.yaml", "TESTAPP")
+		os.Args = []string{"testapp"}
+		v, result, err := loader.LoadWithViper(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().Error(err, "expected error, got nil")
+		s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+		s.Require().Nil(v, "expected nil viper instance")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_StrictKeys() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+		DB   struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name          string
+		content       string
+		opts          []Option
+		args          []string
+		expectedError string
+	}{
+		{
+			name:    "known keys",
+			content: "port: 80\ndb:\n  url: localhost\n",
+			opts:    []Option{WithStrictKeys()},
+		},
+		{
+			name:    "known keys with config flag",
+			content: "port: 80\n",
+			opts:    []Option{WithStrictKeys()},
+			args:    []string{"--config", ""}, // Will be set in test.
+		},
+		{
+			name:    "unknown key without option",
+			content: "prot: 80\n",
+		},
+		{
+			name:          "unknown key",
+			content:       "prot: 80\n",
+			opts:          []Option{WithStrictKeys()},
+			expectedError: "unknown config keys: prot",
+		},
+		{
+			name:          "nested and multiple unknown keys",
+			content:       "prot: 80\ndb:\n  url: localhost\n  uri: localhost\n",
+			opts:          []Option{WithStrictKeys()},
+			expectedError: "unknown config keys: db.uri, prot",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			if len(tC.args) > 0 && tC.args[0] == "--config" {
+				tC.args[1] = configPath
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_WeaklyTypedInput() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		opts          []Option
+		expectedPort  int
+		expectedError error
+	}{
+		{
+			name:         "enabled by default",
+			expectedPort: 7070,
+		},
+		{
+			name:         "explicitly enabled",
+			opts:         []Option{WithWeaklyTypedInput(true)},
+			expectedPort: 7070,
+		},
+		{
+			name:          "disabled",
+			opts:          []Option{WithWeaklyTypedInput(false)},
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			s.T().Setenv("TESTAPP_PORT", "7070")
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Profiles() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		args           []string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:           "no profile",
+			opts:           []Option{WithProfiles()},
+			expectedConfig: testConfig{LogLevel: "info", Port: 8080},
+		},
+		{
+			name:           "dev profile",
+			opts:           []Option{WithProfiles()},
+			args:           []string{"--env", "dev"},
+			expectedConfig: testConfig{LogLevel: "debug", Port: 8080},
+		},
+		{
+			name:           "prod profile",
+			opts:           []Option{WithProfiles("dev", "prod")},
+			args:           []string{"-e", "prod"},
+			expectedConfig: testConfig{LogLevel: "error", Port: 443},
+		},
+		{
+			name:           "profile from env",
+			opts:           []Option{WithProfiles()},
+			envVars:        map[string]string{"TESTAPP_ENV": "prod"},
+			expectedConfig: testConfig{LogLevel: "error", Port: 443},
+		},
+		{
+			name:          "profile not in allowed list",
+			opts:          []Option{WithProfiles("dev", "prod")},
+			args:          []string{"--env", "staging"},
+			expectedError: `unknown profile "staging": expected one of dev, prod`,
+		},
+		{
+			name:          "profile without file",
+			opts:          []Option{WithProfiles()},
+			args:          []string{"--env", "staging"},
+			expectedError: `unknown profile "staging": config file not found`,
+		},
+		{
+			name:          "flag without option",
+			args:          []string{"--env", "dev"},
+			expectedError: "unknown flag",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("log_level: info\nport: 8080\n"))
+			dir := filepath.Dir(configPath)
+			s.Require().NoError(os.WriteFile(filepath.Join(dir, "config.dev.yaml"), []byte("log_level: debug\n"), 0o600))
+			s.Require().NoError(os.WriteFile(filepath.Join(dir, "config.prod.yaml"), []byte("log_level: error\nport: 443\n"), 0o600))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Validator() {
+	type testConfig struct {
+		TLS struct {
+			Enabled  bool   `mapstructure:"enabled"`
+			CertPath string `mapstructure:"cert_path"`
+		} `mapstructure:"tls"`
+	}
+
+	errNoCert := errors.New("cert path is required when TLS is enabled")
+	validator := func(cfg any) error {
+		c, ok := cfg.(*testConfig)
+		if !ok {
+			return fmt.Errorf("unexpected config type %T", cfg)
+		}
+		if c.TLS.Enabled && c.TLS.CertPath == "" {
+			return errNoCert
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name          string
+		content       string
+		args          []string
+		expected      LoadResult
+		expectedError error
+	}{
+		{
+			name:     "valid config",
+			content:  "tls:\n  enabled: true\n  cert_path: /etc/cert.pem\n",
+			expected: LoadResultContinue,
+		},
+		{
+			name:          "rejected config",
+			content:       "tls:\n  enabled: true\n",
+			expected:      LoadResultStop,
+			expectedError: errNoCert,
+		},
+		{
+			name:     "not called on version",
+			content:  "tls:\n  enabled: true\n",
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "not called on help",
+			content:  "tls:\n  enabled: true\n",
+			args:     []string{"--help"},
+			expected: LoadResultStop,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithValidator(validator))
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_DetailedResults() {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		args     []string
+		expected LoadResult
+	}{
+		{
+			name:     "version",
+			opts:     []Option{WithDetailedResults()},
+			args:     []string{"--version"},
+			expected: LoadResultVersion,
+		},
+		{
+			name:     "help",
+			opts:     []Option{WithDetailedResults()},
+			args:     []string{"--help"},
+			expected: LoadResultHelp,
+		},
+		{
+			name:     "help and version",
+			opts:     []Option{WithDetailedResults()},
+			args:     []string{"--version", "--help"},
+			expected: LoadResultHelp,
+		},
+		{
+			name:     "check config",
+			opts:     []Option{WithDetailedResults(), WithCheckConfig()},
+			args:     []string{"--check-config"},
+			expected: LoadResultCheckConfig,
+		},
+		{
+			name:     "continue",
+			opts:     []Option{WithDetailedResults()},
+			expected: LoadResultContinue,
+		},
+		{
+			name:     "version without option",
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "help without option",
+			args:     []string{"--help"},
+			expected: LoadResultStop,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs(tC.args, &struct{}{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expected != LoadResultContinue, result.IsStop(), "unexpected IsStop value")
+		})
+	}
+}
+
+// fakeRemoteConfig is a fake viper remote config factory, serving data by provider path.
+type fakeRemoteConfig struct {
+	data map[string]string
+}
+
+func (f *fakeRemoteConfig) Get(rp viper.RemoteProvider) (io.Reader, error) {
+	data, ok := f.data[rp.Path()]
+	if !ok {
+		return nil, fmt.Errorf("connection refused: %s", rp.Endpoint())
+	}
+	return bytes.NewReader([]byte(data)), nil
+}
+
+func (f *fakeRemoteConfig) Watch(rp viper.RemoteProvider) (io.Reader, error) {
+	return f.Get(rp)
+}
+
+func (f *fakeRemoteConfig) WatchChannel(viper.RemoteProvider) (<-chan *viper.RemoteResponse, chan bool) {
+	return nil, nil
+}
+
+func (s *LoaderSuite) TestLoad_RemoteProvider() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+		Feature  bool   `mapstructure:"feature"`
+	}
+
+	testCases := []struct {
+		name           string
+		remote         *fakeRemoteConfig
+		opts           []Option
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:   "merged under file config",
+			remote: &fakeRemoteConfig{data: map[string]string{"/config/testapp": `{"port": 9090, "feature": true}`}},
+			opts: []Option{
+				WithRemoteProvider("etcd3", "http://127.0.0.1:2379", "/config/testapp", "json"),
+			},
+			expectedConfig: testConfig{LogLevel: "info", Port: 8080, Feature: true},
+		},
+		{
+			name:   "network error",
+			remote: &fakeRemoteConfig{},
+			opts: []Option{
+				WithRemoteProvider("etcd3", "http://127.0.0.1:2379", "/config/testapp", "json"),
+			},
+			expectedError: `read remote config from etcd3 at "http://127.0.0.1:2379"`,
+		},
+		{
+			name: "remote support not enabled",
+			opts: []Option{
+				WithRemoteProvider("etcd3", "http://127.0.0.1:2379", "/config/testapp", "json"),
+			},
+			expectedError: "blank import of the viper/remote package",
+		},
+		{
+			name:   "unsupported provider",
+			remote: &fakeRemoteConfig{},
+			opts: []Option{
+				WithRemoteProvider("zookeeper", "127.0.0.1:2181", "/config/testapp", "json"),
+			},
+			expectedError: `add remote provider "zookeeper"`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			if tC.remote != nil {
+				viper.RemoteConfig = tC.remote
+				defer func() { viper.RemoteConfig = nil }()
+			}
+
+			configPath := s.writeConfigFile("config.yaml", []byte("log_level: info\nport: 8080\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Subcommands() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name         string
+		args         []string
+		opts         []Option
+		expected     LoadResult
+		expectedPort int  // Port, observed by the subcommand.
+		expectedRun  bool // Whether the subcommand was run.
+	}{
+		{
+			name:         "subcommand reads loaded config",
+			args:         []string{"migrate"},
+			expected:     LoadResultStop,
+			expectedPort: 8080,
+			expectedRun:  true,
+		},
+		{
+			name:         "subcommand with config flag",
+			args:         []string{"migrate", "--config", ""}, // Will be set in test.
+			opts:         []Option{WithDetailedResults()},
+			expected:     LoadResultCommand,
+			expectedPort: 9090,
+			expectedRun:  true,
+		},
+		{
+			name:     "root command does not run subcommand",
+			args:     []string{},
+			expected: LoadResultContinue,
+		},
+		{
+			name:     "subcommand help",
+			args:     []string{"migrate", "--help"},
+			opts:     []Option{WithDetailedResults()},
+			expected: LoadResultHelp,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			overridePath := filepath.Join(filepath.Dir(configPath), "override.yaml")
+			s.Require().NoError(os.WriteFile(overridePath, []byte("port: 9090\n"), 0o600), "write config file")
+			if len(tC.args) > 2 && tC.args[1] == "--config" {
+				tC.args[2] = overridePath
+			}
+
+			cfg := &testConfig{}
+			var run bool
+			var observedPort int
+			migrate := &cobra.Command{
+				Use: "migrate",
+				Run: func(_ *cobra.Command, _ []string) {
+					run = true
+					observedPort = cfg.Port
+				},
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			loader.AddCommand(migrate)
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expectedRun, run, "unexpected subcommand run state")
+			s.Require().Equal(tC.expectedPort, observedPort, "unexpected port observed by subcommand")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_RunE() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	errRun := errors.New("service failed")
+
+	testCases := []struct {
+		name          string
+		content       string
+		args          []string
+		runErr        error
+		expected      LoadResult
+		expectedRun   bool
+		expectedError error
+	}{
+		{
+			name:        "run after successful load",
+			content:     "port: 8080\n",
+			expected:    LoadResultContinue,
+			expectedRun: true,
+		},
+		{
+			name:          "run error",
+			content:       "port: 8080\n",
+			runErr:        errRun,
+			expected:      LoadResultStop,
+			expectedRun:   true,
+			expectedError: errRun,
+		},
+		{
+			name:          "not run on failed load",
+			content:       "port: not-a-number\n",
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+		{
+			name:     "not run on version",
+			content:  "port: 8080\n",
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "not run on help",
+			content:  "port: 8080\n",
+			args:     []string{"--help"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "not run on check config",
+			content:  "port: 8080\n",
+			args:     []string{"--check-config"},
+			expected: LoadResultStop,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			var run bool
+			runE := func(cfg any) error {
+				run = true
+				s.Require().Equal(8080, cfg.(*testConfig).Port, "entrypoint must receive the loaded config")
+				return tC.runErr
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithRunE(runE), WithCheckConfig())
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			switch {
+			case errors.Is(tC.expectedError, errSomeError):
+				s.Require().Error(err, "expected error, got nil")
+			case tC.expectedError != nil:
+				s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+			default:
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expectedRun, run, "unexpected entrypoint run state")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_HelpOutput() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+		Ignored string `mapstructure:"-"`
+	}
+
+	testCases := []struct {
+		name       string
+		opts       []Option
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:     "default help",
+			expected: []string{"Test App", "--config"},
+			unexpected: []string{
+				"Environment Variables:",
+				"Examples:",
+			},
+		},
+		{
+			name: "env variables section",
+			opts: []Option{WithEnvHelp()},
+			expected: []string{
+				"Test App\n\nEnvironment Variables:",
+				"TESTAPP_LOG_LEVEL",
+				"TESTAPP_PORT",
+				"TESTAPP_DB_URL",
+				"TESTAPP_DB_POOL_SIZE",
+				"db.pool_size",
+			},
+			unexpected: []string{"TESTAPP_IGNORED"},
+		},
+		{
+			name: "env variables with custom replacer",
+			opts: []Option{WithEnvHelp(), WithEnvKeyReplacer(strings.NewReplacer(".", "__"))},
+			expected: []string{
+				"TESTAPP_DB__POOL_SIZE",
+			},
+		},
+		{
+			name:       "env variables with env disabled",
+			opts:       []Option{WithEnvHelp(), WithoutEnv()},
+			unexpected: []string{"Environment Variables:"},
+		},
+		{
+			name:     "examples",
+			opts:     []Option{WithHelpExamples("  testapp --config prod.yaml")},
+			expected: []string{"Examples:\n  testapp --config prod.yaml"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP", tC.opts...)
+			buf := &bytes.Buffer{}
+			result, err := loader.LoadArgs([]string{"--help"}, &testConfig{}, PlainVersionPrinter("v1.0.0"), buf)
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+			for _, str := range tC.expected {
+				s.Require().Contains(buf.String(), str, "expected help output to contain %q", str)
+			}
+			for _, str := range tC.unexpected {
+				s.Require().NotContains(buf.String(), str, "expected help output not to contain %q", str)
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_JSONC() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Port     int    `mapstructure:"port"`
+		DB       struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+		Comment string `mapstructure:"comment"`
+	}
+
+	content := `// Service config.
+{
+	"log_level": "info", // Trailing comment.
+	/* Block comment
+	   spanning multiple lines. */
+	"port": /* inline */ 8080,
+	"db": {
+		"url": "postgres://localhost:5432/db" // Slashes inside a string are kept.
+	},
+	"comment": "not /* a comment */, \"// neither\""
+}
+`
+	expected := testConfig{LogLevel: "info", Port: 8080, Comment: `not /* a comment */, "// neither"`}
+	expected.DB.URL = "postgres://localhost:5432/db"
+
+	s.Run("file", func() {
+		configPath := s.writeConfigFile("config.jsonc", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &testConfig{}
+		result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+	})
+
+	s.Run("file with env override", func() {
+		configPath := s.writeConfigFile("config.jsonc", []byte(content))
+		s.T().Setenv("TESTAPP_PORT", "9090")
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(9090, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, 9090)
+	})
+
+	s.Run("reader", func() {
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP")
+		cfg := &testConfig{}
+		result, err := loader.LoadFromReader(cfg, bytes.NewReader([]byte(content)), "jsonc")
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+	})
+
+	s.Run("plain json rejects comments", func() {
+		configPath := s.writeConfigFile("config.json", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().Error(err, "expected error, got nil")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_PointerFields() {
+	type replicaConfig struct {
+		URL string `mapstructure:"url"`
+	}
+	type dbConfig struct {
+		URL     string         `mapstructure:"url"`
+		Replica *replicaConfig `mapstructure:"replica"`
+	}
+	type testConfig struct {
+		Port  int       `mapstructure:"port"`
+		DB    *dbConfig `mapstructure:"db"`
+		Cache *struct {
+			TTL int `mapstructure:"ttl"`
+		} `mapstructure:"cache"`
+	}
+
+	testCases := []struct {
+		name           string
+		content        string
+		envVars        map[string]string
+		expectedConfig testConfig
+	}{
+		{
+			name:           "absent sections stay nil",
+			content:        "port: 8080\n",
+			expectedConfig: testConfig{Port: 8080},
+		},
+		{
+			name:           "present section is allocated",
+			content:        "port: 8080\ndb:\n  url: localhost:5432\n",
+			expectedConfig: testConfig{Port: 8080, DB: &dbConfig{URL: "localhost:5432"}},
+		},
+		{
+			name:    "nested pointer is allocated",
+			content: "db:\n  url: localhost:5432\n  replica:\n    url: replica:5432\n",
+			expectedConfig: testConfig{
+				DB: &dbConfig{URL: "localhost:5432", Replica: &replicaConfig{URL: "replica:5432"}},
+			},
+		},
+		{
+			name:    "env override of a pointer field",
+			content: "db:\n  url: localhost:5432\n",
+			envVars: map[string]string{"TESTAPP_DB_URL": "prod:5432"},
+			expectedConfig: testConfig{
+				DB: &dbConfig{URL: "prod:5432"},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_AllowNilWriter() {
+	testCases := []struct {
+		name          string
+		opts          []Option
+		args          []string
+		expected      LoadResult
+		expectedError error
+	}{
+		{
+			name:     "version with discarded output",
+			opts:     []Option{WithAllowNilWriter()},
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "help with discarded output",
+			opts:     []Option{WithAllowNilWriter()},
+			args:     []string{"--help"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "regular load",
+			opts:     []Option{WithAllowNilWriter()},
+			expected: LoadResultContinue,
+		},
+		{
+			name:          "nil writer without option",
+			args:          []string{"--version"},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs(tC.args, &struct{}{}, PlainVersionPrinter("v1.0.0"), nil)
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigName() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		files         map[string]string // Relative path -> content.
+		searchPaths   []string          // Relative to the temp dir.
+		args          []string
+		opts          []Option
+		expectedPort  int
+		expectedError string
+	}{
+		{
+			name:         "yaml discovered",
+			files:        map[string]string{"conf/config.yaml": "port: 8080\n"},
+			searchPaths:  []string{"conf"},
+			expectedPort: 8080,
+		},
+		{
+			name:         "toml discovered",
+			files:        map[string]string{"conf/config.toml": "port = 9090\n"},
+			searchPaths:  []string{"conf"},
+			expectedPort: 9090,
+		},
+		{
+			name: "first search path wins",
+			files: map[string]string{
+				"first/config.json":  `{"port": 1111}`,
+				"second/config.yaml": "port: 2222\n",
+			},
+			searchPaths:  []string{"missing", "first", "second"},
+			expectedPort: 1111,
+		},
+		{
+			name: "explicit config flag overrides",
+			files: map[string]string{
+				"conf/config.yaml":  "port: 8080\n",
+				"other/custom.yaml": "port: 7070\n",
+			},
+			searchPaths:  []string{"conf"},
+			args:         []string{"--config", "other/custom.yaml"},
+			expectedPort: 7070,
+		},
+		{
+			name:          "not found",
+			files:         map[string]string{"conf/other.yaml": "port: 8080\n"},
+			searchPaths:   []string{"conf"},
+			expectedError: `config file "config" not found in`,
+		},
+		{
+			name:         "not found falls back to embedded default",
+			searchPaths:  []string{"conf"},
+			opts:         []Option{WithEmbeddedDefault(embeddedFS, "testdata/embedded/config.yaml", "yaml")},
+			expectedPort: 8080,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			dir := s.T().TempDir()
+			for name, content := range tC.files {
+				path := filepath.Join(dir, name)
+				s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o700), "create config dir")
+				s.Require().NoError(os.WriteFile(path, []byte(content), 0o600), "write config file")
+			}
+			searchPaths := make([]string, 0, len(tC.searchPaths))
+			for _, p := range tC.searchPaths {
+				searchPaths = append(searchPaths, filepath.Join(dir, p))
+			}
+			args := slices.Clone(tC.args)
+			if len(args) > 1 && args[0] == "--config" {
+				args[1] = filepath.Join(dir, args[1])
+			}
+
+			opts := append([]Option{WithConfigName("config", searchPaths...)}, tC.opts...)
+			loader := NewLoader("testapp", "Test App", "", "", "TESTAPP", opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigParseError() {
+	testCases := []struct {
+		name         string
+		fileName     string
+		content      string
+		expectedLine int
+	}{
+		{
+			name:         "yaml syntax error",
+			fileName:     "config.yaml",
+			content:      "port: 8080\nhost: localhost\n  broken: [\n",
+			expectedLine: 3,
+		},
+		{
+			name:         "yaml mapping error",
+			fileName:     "config.yaml",
+			content:      "port: 8080\nhost: a: b\n",
+			expectedLine: 2,
+		},
+		{
+			name:     "json syntax error",
+			fileName: "config.json",
+			content:  `{"port": 8080,}`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile(tC.fileName, []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			result, err := loader.LoadArgs(nil, &struct{}{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().Error(err, "expected error, got nil")
+			s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+			var parseErr *ConfigParseError
+			s.Require().ErrorAs(err, &parseErr, "expected ConfigParseError, got %T", err)
+			s.Require().Equal(configPath, parseErr.Path, "unexpected path: got %q, want %q", parseErr.Path, configPath)
+			s.Require().Equal(tC.expectedLine, parseErr.Line, "unexpected line: got %d, want %d", parseErr.Line, tC.expectedLine)
+			s.Require().ErrorContains(err, configPath, "error must mention the path")
+			if tC.expectedLine > 0 {
+				s.Require().ErrorContains(err, fmt.Sprintf("line %d", tC.expectedLine), "error must mention the line")
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_OptionalConfigFile() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		configPath    string // Relative to the temp dir. Empty means no path at all.
+		content       string // Written to configPath if non-empty.
+		args          []string
+		opts          []Option
+		expectedPort  int
+		expectedError error
+	}{
+		{
+			name:       "missing default path",
+			configPath: "config.yaml",
+			opts:       []Option{WithOptionalConfigFile()},
+		},
+		{
+			name:       "missing explicit path",
+			configPath: "config.yaml",
+			args:       []string{"--config", "missing.yaml"},
+			opts:       []Option{WithOptionalConfigFile()},
+		},
+		{
+			name: "no path",
+			opts: []Option{WithOptionalConfigFile()},
+		},
+		{
+			name: "missing searched file",
+			opts: []Option{WithOptionalConfigFile(), WithConfigName("config", "missing")},
+		},
+		{
+			name:         "existing file is read",
+			configPath:   "config.yaml",
+			content:      "port: 8080\n",
+			opts:         []Option{WithOptionalConfigFile()},
+			expectedPort: 8080,
+		},
+		{
+			name:          "malformed file is an error",
+			configPath:    "config.yaml",
+			content:       "port: 8080\nhost: a: b\n",
+			opts:          []Option{WithOptionalConfigFile()},
+			expectedError: errSomeError,
+		},
+		{
+			name:          "missing default path without option",
+			configPath:    "config.yaml",
+			expectedError: errSomeError,
+		},
+		{
+			name:          "missing explicit path without option",
+			configPath:    "config.yaml",
+			content:       "port: 8080\n",
+			args:          []string{"--config", "missing.yaml"},
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			dir := s.T().TempDir()
+			configPath := ""
+			if tC.configPath != "" {
+				configPath = filepath.Join(dir, tC.configPath)
+			}
+			if tC.content != "" {
+				s.Require().NoError(os.WriteFile(configPath, []byte(tC.content), 0o600), "write config file")
+			}
+			args := slices.Clone(tC.args)
+			if len(args) > 1 && args[0] == "--config" {
+				args[1] = filepath.Join(dir, args[1])
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvBindings() {
+	type dbConfig struct {
+		Password string `mapstructure:"password"`
+	}
+	type testConfig struct {
+		Port      int      `mapstructure:"port"`
+		SecretKey string   `mapstructure:"secret_key"`
+		DB        dbConfig `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		envVars        map[string]string
+		expectedConfig testConfig
+	}{
+		{
+			name:           "struct keys are bound implicitly",
+			envVars:        map[string]string{"TESTAPP_SECRET_KEY": "s3cr3t", "TESTAPP_DB_PASSWORD": "pa55"},
+			expectedConfig: testConfig{Port: 8080, SecretKey: "s3cr3t", DB: dbConfig{Password: "pa55"}},
+		},
+		{
+			name:           "bound keys are read from env",
+			opts:           []Option{WithEnvBindings("secret_key", "db.password")},
+			envVars:        map[string]string{"TESTAPP_SECRET_KEY": "s3cr3t", "TESTAPP_DB_PASSWORD": "pa55"},
+			expectedConfig: testConfig{Port: 8080, SecretKey: "s3cr3t", DB: dbConfig{Password: "pa55"}},
+		},
+		{
+			name:           "bound key without env variable",
+			opts:           []Option{WithEnvBindings("secret_key")},
+			expectedConfig: testConfig{Port: 8080},
+		},
+		{
+			name:           "bound keys with env disabled",
+			opts:           []Option{WithEnvBindings("secret_key"), WithoutEnv()},
+			envVars:        map[string]string{"TESTAPP_SECRET_KEY": "s3cr3t"},
+			expectedConfig: testConfig{Port: 8080},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_FlagCollisions() {
+	testCases := []struct {
+		name          string
+		opts          []Option
+		defineFlags   func(cmd *cobra.Command) // Defines flags of the nested subcommand.
+		expectedError string
+	}{
+		{
+			name:          "name collision",
+			defineFlags:   func(cmd *cobra.Command) { cmd.Flags().String("config", "", "") },
+			expectedError: `flag --config of command "testapp migrate up" collides with the built-in flag --config`,
+		},
+		{
+			name:          "shorthand collision",
+			defineFlags:   func(cmd *cobra.Command) { cmd.Flags().BoolP("check", "c", false, "") },
+			expectedError: `flag --check of command "testapp migrate up": shorthand -c collides with the built-in flag --config`,
+		},
+		{
+			name:          "persistent flag collision",
+			opts:          []Option{WithProfiles()},
+			defineFlags:   func(cmd *cobra.Command) { cmd.PersistentFlags().StringP("environment", "e", "", "") },
+			expectedError: `shorthand -e collides with the built-in flag --env`,
+		},
+		{
+			name:        "local root flags do not collide",
+			defineFlags: func(cmd *cobra.Command) { cmd.Flags().BoolP("version", "v", false, "") },
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			migrate := &cobra.Command{Use: "migrate"}
+			up := &cobra.Command{Use: "up", Run: func(_ *cobra.Command, _ []string) {}}
+			tC.defineFlags(up)
+			migrate.AddCommand(up)
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			loader.AddCommand(migrate)
+			os.Args = []string{"testapp", "migrate", "up"}
+
+			s.Require().NotPanics(func() {
+				// Loading twice, as the subcommands inherit the built-in flags on the first run.
+				for range 2 {
+					result, err := loader.Load(&struct{}{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+					if tC.expectedError != "" {
+						s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+						s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+						continue
+					}
+					s.Require().NoError(err, "expected nil, got error")
+					s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				}
+			}, "unexpected panic")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestKeySources() {
+	type testConfig struct {
+		Port     int    `mapstructure:"port"`
+		LogLevel string `mapstructure:"log_level"`
+		Secret   string `mapstructure:"secret"`
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		args     []string
+		envVars  map[string]string
+		expected map[string]KeySource
+	}{
+		{
+			name:    "file and env",
+			envVars: map[string]string{"TESTAPP_PORT": "7070"},
+			expected: map[string]KeySource{
+				"port":      KeySourceEnv,
+				"log_level": KeySourceFile,
+				"secret":    KeySourceDefault,
+			},
+		},
+		{
+			name:    "env disabled",
+			opts:    []Option{WithoutEnv()},
+			envVars: map[string]string{"TESTAPP_PORT": "7070"},
+			expected: map[string]KeySource{
+				"port":      KeySourceFile,
+				"log_level": KeySourceFile,
+			},
+		},
+		{
+			name: "bound env key without value",
+			opts: []Option{WithEnvBindings("secret")},
+			expected: map[string]KeySource{
+				"port":      KeySourceFile,
+				"log_level": KeySourceFile,
+				"secret":    KeySourceDefault,
+			},
+		},
+		{
+			name:    "bound env key with value",
+			opts:    []Option{WithEnvBindings("secret")},
+			envVars: map[string]string{"TESTAPP_SECRET": "s3cr3t"},
+			expected: map[string]KeySource{
+				"port":      KeySourceFile,
+				"log_level": KeySourceFile,
+				"secret":    KeySourceEnv,
+			},
+		},
+		{
+			name: "config flag",
+			args: []string{"--config", ""}, // Will be set in test.
+			expected: map[string]KeySource{
+				"port":      KeySourceFile,
+				"log_level": KeySourceFile,
+				"secret":    KeySourceDefault,
+				"config":    KeySourceFlag,
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nlog_level: info\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+			args := slices.Clone(tC.args)
+			if len(args) > 1 && args[0] == "--config" {
+				args[1] = configPath
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			os.Args = append([]string{"testapp"}, args...)
+			v, result, err := loader.LoadWithViper(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+
+			sources := loader.KeySources(v)
+			s.Require().Equal(tC.expected, sources, "unexpected key sources: got %v, want %v", sources, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_TimeFields() {
+	type windowConfig struct {
+		End time.Time `mapstructure:"end" timeformat:"02.01.2006 15:04"`
+	}
+	type testConfig struct {
+		StartTime time.Time     `mapstructure:"start_time"`
+		Date      time.Time     `mapstructure:"date" timeformat:"2006-01-02"`
+		Window    *windowConfig `mapstructure:"window"`
+	}
+
+	testCases := []struct {
+		name           string
+		content        string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:    "rfc3339",
+			content: "start_time: \"2024-05-01T10:30:00Z\"\n",
+			expectedConfig: testConfig{
+				StartTime: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "unquoted yaml timestamps",
+			content: "start_time: 2024-05-01T10:30:00Z\ndate: 2024-05-01\n",
+			expectedConfig: testConfig{
+				StartTime: time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC),
+				Date:      time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:    "custom layout",
+			content: "date: \"2024-05-01\"\nwindow:\n  end: \"31.12.2024 23:59\"\n",
+			expectedConfig: testConfig{
+				Date:   time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+				Window: &windowConfig{End: time.Date(2024, 12, 31, 23, 59, 0, 0, time.UTC)},
+			},
+		},
+		{
+			name:    "custom layout from env",
+			content: "date: \"2024-05-01\"\n",
+			envVars: map[string]string{"TESTAPP_DATE": "2025-01-15"},
+			expectedConfig: testConfig{
+				Date: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:          "invalid rfc3339",
+			content:       "start_time: \"01/05/2024\"\n",
+			expectedError: "start_time",
+		},
+		{
+			name:          "invalid custom layout",
+			content:       "window:\n  end: \"2024-12-31\"\n",
+			expectedError: `'window.end' parse time "2024-12-31" with layout "02.01.2006 15:04"`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_FormatNeutralTimes() {
+	type testConfig struct {
+		Timeout time.Duration `mapstructure:"timeout"`
+		Date    time.Time     `mapstructure:"date"`
+	}
+
+	date := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name           string
+		file           string
+		content        string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:           "toml integer and date",
+			file:           "config.toml",
+			content:        "timeout = 30000000000\ndate = 2024-05-01\n",
+			expectedConfig: testConfig{Timeout: 30 * time.Second, Date: date},
+		},
+		{
+			name:           "toml string and local date-time",
+			file:           "config.toml",
+			content:        "timeout = \"30s\"\ndate = 2024-05-01T10:30:00\n",
+			expectedConfig: testConfig{Timeout: 30 * time.Second, Date: date.Add(10*time.Hour + 30*time.Minute)},
+		},
+		{
+			name:           "toml offset date-time",
+			file:           "config.toml",
+			content:        "date = 2024-05-01T00:00:00Z\n",
+			expectedConfig: testConfig{Date: date},
+		},
+		{
+			name:           "yaml string and date",
+			file:           "config.yaml",
+			content:        "timeout: 30s\ndate: 2024-05-01\n",
+			expectedConfig: testConfig{Timeout: 30 * time.Second, Date: date},
+		},
+		{
+			name:           "yaml integer",
+			file:           "config.yaml",
+			content:        "timeout: 30000000000\n",
+			expectedConfig: testConfig{Timeout: 30 * time.Second},
+		},
+		{
+			name:           "json float and date string",
+			file:           "config.json",
+			content:        `{"timeout": 3e10, "date": "2024-05-01"}`,
+			expectedConfig: testConfig{Timeout: 30 * time.Second, Date: date},
+		},
+		{
+			name:           "env string and date",
+			file:           "config.yaml",
+			content:        "timeout: 5s\n",
+			envVars:        map[string]string{"TESTAPP_TIMEOUT": "30s", "TESTAPP_DATE": "2024-05-01"},
+			expectedConfig: testConfig{Timeout: 30 * time.Second, Date: date},
+		},
+		{
+			name:          "env number without unit",
+			file:          "config.yaml",
+			content:       "timeout: 5s\n",
+			envVars:       map[string]string{"TESTAPP_TIMEOUT": "30"},
+			expectedError: `missing unit in duration "30"`,
+		},
+		{
+			name:          "yaml string without unit",
+			file:          "config.yaml",
+			content:       "timeout: \"30\"\n",
+			expectedError: `missing unit in duration "30"`,
+		},
+		{
+			name:          "invalid date",
+			file:          "config.yaml",
+			content:       "date: 01/05/2024\n",
+			expectedError: `parse time "01/05/2024"`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile(tC.file, []byte(tC.content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_StandardHooks() {
+	type testConfig struct {
+		Endpoint *url.URL      `mapstructure:"endpoint"`
+		IP       net.IP        `mapstructure:"ip"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+	}
+
+	expected := testConfig{
+		Endpoint: &url.URL{Scheme: "https", Host: "example.com"},
+		IP:       net.ParseIP("10.0.0.1"),
+		Timeout:  5 * time.Second,
+	}
+
+	testCases := []struct {
+		name           string
+		content        string
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:           "from file",
+			content:        "endpoint: https://example.com\nip: 10.0.0.1\ntimeout: 5s\n",
+			expectedConfig: expected,
+		},
+		{
+			name:    "from env",
+			content: "endpoint: https://old.example.com\nip: 127.0.0.1\ntimeout: 1s\n",
+			envVars: map[string]string{
+				"TESTAPP_ENDPOINT": "https://example.com",
+				"TESTAPP_IP":       "10.0.0.1",
+				"TESTAPP_TIMEOUT":  "5s",
+			},
+			expectedConfig: expected,
+		},
+		{
+			name:          "invalid ip",
+			content:       "ip: 10.0.0.256\n",
+			expectedError: "'ip'",
+		},
+		{
+			name:          "invalid url",
+			content:       "endpoint: \"http://[::1\"\n",
+			expectedError: "'endpoint'",
+		},
+		{
+			name:          "invalid duration",
+			content:       "timeout: forever\n",
+			expectedError: "'timeout'",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "error must mention the field")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig.Endpoint.String(), cfg.Endpoint.String(), "unexpected endpoint")
+			s.Require().True(tC.expectedConfig.IP.Equal(cfg.IP), "unexpected ip: got %v, want %v", cfg.IP, tC.expectedConfig.IP)
+			s.Require().Equal(tC.expectedConfig.Timeout, cfg.Timeout, "unexpected timeout")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvFiles() {
+	type dbConfig struct {
+		Password string `mapstructure:"password"`
+	}
+	type testConfig struct {
+		Port int      `mapstructure:"port"`
+		DB   dbConfig `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		secret         string // Written to a temp file, referenced by the _FILE env variables with an empty value.
+		envVars        map[string]string
+		expectedConfig testConfig
+		expectedError  string
+	}{
+		{
+			name:           "key absent from file",
+			secret:         "pa55\n",
+			envVars:        map[string]string{"TESTAPP_DB_PASSWORD_FILE": ""},
+			expectedConfig: testConfig{Port: 8080, DB: dbConfig{Password: "pa55"}},
+		},
+		{
+			name:           "key present in file",
+			secret:         "9090",
+			envVars:        map[string]string{"TESTAPP_PORT_FILE": ""},
+			expectedConfig: testConfig{Port: 9090},
+		},
+		{
+			name:           "direct variable wins",
+			opts:           []Option{WithEnvBindings("db.password")}, // Key is absent from the config file.
+			secret:         "pa55",
+			envVars:        map[string]string{"TESTAPP_DB_PASSWORD_FILE": "", "TESTAPP_DB_PASSWORD": "direct"},
+			expectedConfig: testConfig{Port: 8080, DB: dbConfig{Password: "direct"}},
+		},
+		{
+			name:           "env disabled",
+			opts:           []Option{WithoutEnv()},
+			secret:         "pa55",
+			envVars:        map[string]string{"TESTAPP_DB_PASSWORD_FILE": ""},
+			expectedConfig: testConfig{Port: 8080},
+		},
+		{
+			name:          "missing file",
+			envVars:       map[string]string{"TESTAPP_DB_PASSWORD_FILE": "/nonexistent/secret"},
+			expectedError: "read TESTAPP_DB_PASSWORD_FILE for 'db.password'",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			secretPath := filepath.Join(filepath.Dir(configPath), "secret")
+			s.Require().NoError(os.WriteFile(secretPath, []byte(tC.secret), 0o600), "write secret file")
+			for k, v := range tC.envVars {
+				if v == "" {
+					v = secretPath
+				}
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_PrintConfig() {
+	type dbConfig struct {
+		URL      string `mapstructure:"url"`
+		Password string `mapstructure:"password" secret:"true"`
+	}
+	type upstreamConfig struct {
+		URL  string `mapstructure:"url"`
+		Pass string `mapstructure:"pass" secret:"true"`
+	}
+	type testConfig struct {
+		Port      int                       `mapstructure:"port"`
+		APIKey    string                    `mapstructure:"api_key" secret:"true"`
+		Token     string                    `mapstructure:"apiToken" secret:"true"`
+		DB        dbConfig                  `mapstructure:"db"`
+		Upstreams map[string]upstreamConfig `mapstructure:"ups"`
+	}
+
+	content := "port: 8080\napi_key: k3y\napiToken: t0ken\ndb:\n  url: postgres://db\n  password: pa55\n" +
+		"ups:\n  a:\n    url: http://a\n    pass: m4psecret\n"
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		args           []string
+		envVars        map[string]string
+		expected       LoadResult
+		expectedError  error
+		expectedOutput []string
+		hiddenOutput   []string
+	}{
+		{
+			name:     "print redacted config",
+			opts:     []Option{WithPrintConfig()},
+			args:     []string{"--print-config"},
+			envVars:  map[string]string{"TESTAPP_PORT": "9090"},
+			expected: LoadResultStop,
+			expectedOutput: []string{
+				"port: \"9090\"", "url: postgres://db", "api_key: '******'", "password: '******'",
+				"apitoken: '******'", "url: http://a", "pass: '******'",
+			},
+			hiddenOutput: []string{"k3y", "pa55", "t0ken", "m4psecret", "config:", "version:"},
+		},
+		{
+			name:           "detailed result",
+			opts:           []Option{WithPrintConfig(), WithDetailedResults()},
+			args:           []string{"--print-config"},
+			expected:       LoadResultPrintConfig,
+			expectedOutput: []string{"port: 8080"},
+		},
+		{
+			name:         "option enabled, flag unset",
+			opts:         []Option{WithPrintConfig()},
+			expected:     LoadResultContinue,
+			hiddenOutput: []string{"port"},
+		},
+		{
+			name:          "flag without option",
+			args:          []string{"--print-config"},
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			var run bool
+			opts := append([]Option{WithRunE(func(any) error { run = true; return nil })}, tC.opts...)
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			buf := &bytes.Buffer{}
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), buf)
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expected == LoadResultContinue, run, "unexpected service run state")
+			for _, want := range tC.expectedOutput {
+				s.Require().Contains(buf.String(), want, "output must contain %q", want)
+			}
+			for _, hidden := range tC.hiddenOutput {
+				s.Require().NotContains(buf.String(), hidden, "output must not contain %q", hidden)
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_PrePostRun() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	errHook := errors.New("hook failed")
+
+	testCases := []struct {
+		name          string
+		content       string
+		args          []string
+		preErr        error
+		postErr       error
+		expected      LoadResult
+		expectedCalls []string
+		expectedError error
+	}{
+		{
+			name:          "hooks around load",
+			content:       "port: 8080\n",
+			expected:      LoadResultContinue,
+			expectedCalls: []string{"pre1", "pre2", "load", "post1", "post2", "run"},
+		},
+		{
+			name:          "pre-run error",
+			content:       "port: 8080\n",
+			preErr:        errHook,
+			expected:      LoadResultStop,
+			expectedCalls: []string{"pre1"},
+			expectedError: errHook,
+		},
+		{
+			name:          "post-run error",
+			content:       "port: 8080\n",
+			postErr:       errHook,
+			expected:      LoadResultStop,
+			expectedCalls: []string{"pre1", "pre2", "load", "post1"},
+			expectedError: errHook,
+		},
+		{
+			name:          "post-run skipped on failed load",
+			content:       "port: not-a-number\n",
+			expected:      LoadResultStop,
+			expectedCalls: []string{"pre1", "pre2"},
+			expectedError: errSomeError,
+		},
+		{
+			name:     "hooks skipped on version",
+			content:  "port: 8080\n",
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			cfg := &testConfig{}
+			var calls []string
+			hook := func(name string, err error) func() error {
+				return func() error {
+					if name == "post1" && cfg.Port == 8080 {
+						calls = append(calls, "load") // The config is loaded by the time post-run hooks fire.
+					}
+					calls = append(calls, name)
+					return err
+				}
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+				WithPreRun(hook("pre1", tC.preErr)),
+				WithPreRun(hook("pre2", nil)),
+				WithPostRun(hook("post1", tC.postErr)),
+				WithPostRun(hook("post2", nil)),
+				WithRunE(func(any) error { calls = append(calls, "run"); return nil }),
+			)
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			switch {
+			case errors.Is(tC.expectedError, errSomeError):
+				s.Require().Error(err, "expected error, got nil")
+			case tC.expectedError != nil:
+				s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+			default:
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expectedCalls, calls, "unexpected hook calls: got %v, want %v", calls, tC.expectedCalls)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_NestedEnv() {
+	type replicaConfig struct {
+		URL string `mapstructure:"url"`
+	}
+	type dbConfig struct {
+		Host    string         `mapstructure:"host"`
+		Replica *replicaConfig `mapstructure:"replica"`
+	}
+	type cacheConfig struct {
+		TTL time.Duration `mapstructure:"ttl"`
+	}
+	type testConfig struct {
+		DB    dbConfig    `mapstructure:"db"`
+		Cache cacheConfig `mapstructure:"cache"`
+	}
+
+	envVars := map[string]string{
+		"TESTAPP_DB_HOST":        "db.local",
+		"TESTAPP_DB_REPLICA_URL": "postgres://replica",
+		"TESTAPP_CACHE_TTL":      "1m",
+	}
+	expected := testConfig{
+		DB:    dbConfig{Host: "db.local", Replica: &replicaConfig{URL: "postgres://replica"}},
+		Cache: cacheConfig{TTL: time.Minute},
+	}
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "keys present in file",
+			content: "db:\n  host: localhost\n  replica:\n    url: postgres://old\ncache:\n  ttl: 1s\n",
+		},
+		{
+			name:    "keys partially present in file",
+			content: "db:\n  host: localhost\n",
+		},
+		{
+			name: "keys absent from file",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Logger() {
+	type testConfig struct {
+		Port     int               `mapstructure:"port"`
+		Host     string            `mapstructure:"host"`
+		Password string            `mapstructure:"password" secret:"true"`
+		APIKey   string            `mapstructure:"apiKey" secret:"true"`
+		Tokens   map[string]string `mapstructure:"tokens" secret:"true"`
+	}
+
+	s.Run("debug logs", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\npassword: pa55\ntokens:\n  api: t0ken\n"))
+		s.T().Setenv("TESTAPP_PASSWORD", "s3cr3t")
+		s.T().Setenv("TESTAPP_PORT", "9090")
+		s.T().Setenv("TESTAPP_APIKEY", "envs3cr3t")
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+			WithLogger(logger), WithOptionalConfigFiles("missing.yaml"))
+		args := []string{"--set", "host=example.com", "--set", "tokens.api=n3wt0ken"}
+		result, err := loader.LoadArgs(args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		logs := buf.String()
+		s.Require().Contains(logs, `msg="read config file" path=`+configPath, "config path must be logged")
+		s.Require().Contains(logs, `msg="optional config file not found, skipping" path=missing.yaml`, "skipped file must be logged")
+		s.Require().Contains(logs, `msg="config key overridden" key=password source=env env=TESTAPP_PASSWORD value=******`,
+			"env override of a secret must be logged masked")
+		s.Require().Contains(logs, `msg="config key overridden" key=apikey source=env env=TESTAPP_APIKEY value=******`,
+			"env override of a mixed-case secret key must be logged masked")
+		s.Require().Contains(logs, `msg="config key overridden" key=port source=env env=TESTAPP_PORT value=9090`,
+			"env override must be logged with its value")
+		s.Require().Contains(logs, `msg="config key overridden" key=host source=flag value=example.com`,
+			"flag override must be logged with its value")
+		s.Require().Contains(logs, `msg="config key overridden" key=tokens.api source=flag value=******`,
+			"flag override of a secret map entry must be logged masked")
+		for _, secret := range []string{"s3cr3t", "envs3cr3t", "pa55", "t0ken"} {
+			s.Require().NotContains(logs, secret, "secret values must not be logged")
+		}
+	})
+
+	s.Run("silent by default", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		buf := &bytes.Buffer{}
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+		defer slog.SetDefault(defaultLogger)
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Empty(buf.String(), "unexpected log output")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_EnvTag() {
+	type dbConfig struct {
+		URL  string `mapstructure:"url" env:"LEGACY_DB_URL"`
+		Name string `mapstructure:"name"`
+	}
+	type testConfig struct {
+		DB dbConfig `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		envVars        map[string]string
+		expectedConfig testConfig
+	}{
+		{
+			name:           "tagged variable",
+			envVars:        map[string]string{"LEGACY_DB_URL": "postgres://legacy"},
+			expectedConfig: testConfig{DB: dbConfig{URL: "postgres://legacy", Name: "app"}},
+		},
+		{
+			name:           "tagged variable wins over derived one",
+			envVars:        map[string]string{"LEGACY_DB_URL": "postgres://legacy", "TESTAPP_DB_URL": "postgres://derived"},
+			expectedConfig: testConfig{DB: dbConfig{URL: "postgres://legacy", Name: "app"}},
+		},
+		{
+			name:           "derived variable as a fallback",
+			envVars:        map[string]string{"TESTAPP_DB_URL": "postgres://derived"},
+			expectedConfig: testConfig{DB: dbConfig{URL: "postgres://derived", Name: "app"}},
+		},
+		{
+			name:           "replacer is not applied",
+			opts:           []Option{WithEnvKeyReplacer(strings.NewReplacer(".", "__", "_", "__"))},
+			envVars:        map[string]string{"LEGACY_DB_URL": "postgres://legacy"},
+			expectedConfig: testConfig{DB: dbConfig{URL: "postgres://legacy", Name: "app"}},
+		},
+		{
+			name:           "env disabled",
+			opts:           []Option{WithoutEnv()},
+			envVars:        map[string]string{"LEGACY_DB_URL": "postgres://legacy"},
+			expectedConfig: testConfig{DB: dbConfig{URL: "postgres://file", Name: "app"}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("db:\n  url: postgres://file\n  name: app\n"))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedConfig, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expectedConfig)
+		})
+	}
+
+	s.Run("reported as env source", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("db:\n  url: postgres://file\n  name: app\n"))
+		s.T().Setenv("LEGACY_DB_URL", "postgres://legacy")
+		os.Args = []string{"testapp"}
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithLogger(logger))
+
+		v, _, err := loader.LoadWithViper(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		sources := loader.KeySources(v)
+		s.Require().Equal(KeySourceEnv, sources["db.url"], "tagged variable must be reported as env")
+		s.Require().Equal(KeySourceFile, sources["db.name"], "unexpected source of db.name")
+		s.Require().Contains(buf.String(), `key=db.url source=env env=LEGACY_DB_URL value=postgres://legacy`,
+			"tagged variable must be logged")
+
+		values, _, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().True(values.FieldWasSet("db.url"), "tagged variable must count as set")
+		s.Require().Contains(values.SetKeys(), "db.url", "tagged variable must be listed in the set keys")
+
+		report, err := loader.LoadDetailed(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal([]string{"db.url"}, report.EnvOverrides, "unexpected env overrides")
+	})
+
+	s.Run("help output", func() {
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP", WithEnvHelp())
+		help := loader.helpLong(loader.newViper(), &testConfig{})
+		s.Require().Regexp(`LEGACY_DB_URL\s+db\.url`, help, "tagged variable must be listed")
+		s.Require().Regexp(`TESTAPP_DB_NAME\s+db\.name`, help, "derived variable must be listed")
+	})
+}
+
+func (s *LoaderSuite) TestWatchSignal() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("signals can't be sent to the process on windows")
+	}
+
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	s.Run("reload on signal", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		loader := NewLoader("testapp", "Test App", "", "default.yaml", "TESTAPP")
+		cfg := &testConfig{}
+		os.Args = []string{"testapp"} // Reload reuses the flags of the last load instead of parsing os.Args.
+		args := []string{"--config", configPath}
+		result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+
+		process, err := os.FindProcess(os.Getpid())
+		s.Require().NoError(err, "find current process")
+		reloaded := make(chan error)
+		stop, err := loader.WatchSignal(cfg, syscall.SIGHUP, func(err error) { reloaded <- err })
+		s.Require().NoError(err, "expected nil, got error")
+		defer stop()
+
+		// Successful reload, the config path is taken from the flag.
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: 9090\n"), 0o600), "write config file")
+		s.Require().NoError(process.Signal(syscall.SIGHUP), "send signal")
+		s.Require().NoError(<-reloaded, "expected nil, got error")
+		s.Require().Equal(9090, cfg.Port, "unexpected port after reload")
+
+		// Failed reload keeps the previous config.
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: not-a-number\n"), 0o600), "write config file")
+		s.Require().NoError(process.Signal(syscall.SIGHUP), "send signal")
+		s.Require().Error(<-reloaded, "expected error, got nil")
+		s.Require().Equal(9090, cfg.Port, "previous config must be kept")
+
+		stop()
+		stop() // Must be safe to call twice.
+	})
+
+	s.Run("invalid arguments", func() {
+		loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP")
+		_, err := loader.WatchSignal(testConfig{}, syscall.SIGHUP, nil)
+		s.Require().Error(err, "expected error for non-pointer cfg")
+		_, err = loader.WatchSignal(&testConfig{}, nil, nil)
+		s.Require().Error(err, "expected error for nil signal")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_HCL() {
+	type replicaConfig struct {
+		URL string `mapstructure:"url"`
+	}
+	type dbConfig struct {
+		Host    string        `mapstructure:"host"`
+		Port    int           `mapstructure:"port"`
+		Replica replicaConfig `mapstructure:"replica"`
+	}
+	type serviceConfig struct {
+		Port int `mapstructure:"port"`
+	}
+	type testConfig struct {
+		Name     string                   `mapstructure:"name"`
+		Tags     []string                 `mapstructure:"tags"`
+		DB       dbConfig                 `mapstructure:"db"`
+		Services map[string]serviceConfig `mapstructure:"service"`
+	}
+
+	content := `
+name = "svc"
+tags = ["a", "b"]
+
+db {
+  host = "localhost"
+  port = 5432
+
+  replica {
+    url = "postgres://replica"
+  }
+}
+
+service "web" {
+  port = 80
+}
+
+service "api" {
+  port = 81
+}
+`
+	expected := testConfig{
+		Name: "svc",
+		Tags: []string{"a", "b"},
+		DB: dbConfig{
+			Host:    "localhost",
+			Port:    5432,
+			Replica: replicaConfig{URL: "postgres://replica"},
+		},
+		Services: map[string]serviceConfig{"web": {Port: 80}, "api": {Port: 81}},
+	}
+
+	testCases := []struct {
+		name           string
+		envVars        map[string]string
+		expectedConfig func(cfg testConfig) testConfig
+	}{
+		{
+			name:           "blocks map to nested structs",
+			expectedConfig: func(cfg testConfig) testConfig { return cfg },
+		},
+		{
+			name:    "env override of a block value",
+			envVars: map[string]string{"TESTAPP_DB_HOST": "db.local", "TESTAPP_DB_REPLICA_URL": "postgres://env"},
+			expectedConfig: func(cfg testConfig) testConfig {
+				cfg.DB.Host = "db.local"
+				cfg.DB.Replica.URL = "postgres://env"
+				return cfg
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.hcl", []byte(content))
+			for k, v := range tC.envVars {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			want := tC.expectedConfig(expected)
+			s.Require().Equal(want, *cfg, "unexpected config: got %+v, want %+v", *cfg, want)
+		})
+	}
+
+	s.Run("reader", func() {
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP")
+		cfg := &testConfig{}
+		result, err := loader.LoadFromReader(cfg, strings.NewReader(content), "hcl")
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+	})
+
+	s.Run("malformed", func() {
+		configPath := s.writeConfigFile("config.hcl", []byte("db {\n  host = \n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		var parseErr *ConfigParseError
+		s.Require().ErrorAs(err, &parseErr, "expected ConfigParseError, got %v", err)
+		s.Require().Positive(parseErr.Line, "expected line of the error: %v", err)
+	})
+}
+
+func (s *LoaderSuite) TestReload() {
+	type dbConfig struct {
+		Host string `mapstructure:"host"`
+	}
+	type testConfig struct {
+		Port int       `mapstructure:"port"`
+		DB   *dbConfig `mapstructure:"db"`
+	}
+
+	s.Run("independent values", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\ndb:\n  host: localhost\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		os.Args = []string{"testapp", "--config", "ignored.yaml"} // os.Args is not parsed on reload.
+
+		first, result, err := Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(testConfig{Port: 8080, DB: &dbConfig{Host: "localhost"}}, *first, "unexpected first config")
+
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: 9090\ndb:\n  host: db.local\n"), 0o600), "write config file")
+		second, result, err := Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(testConfig{Port: 9090, DB: &dbConfig{Host: "db.local"}}, *second, "unexpected second config")
+
+		s.Require().NotSame(first.DB, second.DB, "values must not share state")
+		s.Require().Equal(testConfig{Port: 8080, DB: &dbConfig{Host: "localhost"}}, *first, "first config must be intact")
+	})
+
+	s.Run("flags of the last load", func() {
+		defaultPath := s.writeConfigFile("config.yaml", []byte("port: 1\n"))
+		otherPath := s.writeConfigFile("other.yaml", []byte("port: 2\n"))
+		loader := NewLoader("testapp", "Test App", "", defaultPath, "TESTAPP")
+		os.Args = []string{"testapp"}
+
+		args := []string{"--config", otherPath, "--set", "db.host=flag.local"}
+		var loaded testConfig
+		_, err := loader.LoadArgs(args, &loaded, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(2, loaded.Port, "unexpected loaded port")
+
+		cfg, result, err := Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(testConfig{Port: 2, DB: &dbConfig{Host: "flag.local"}}, *cfg, "flags of the load must be reused")
+
+		// MergeReload and WatchSignal (sharing loadFresh) read the same config.
+		fresh, _, err := loader.loadFresh(&testConfig{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(*cfg, *fresh.Interface().(*testConfig), "reloads must agree")
+	})
+
+	s.Run("config path from env", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 7070\n"))
+		s.T().Setenv("TESTAPP_CONFIG", configPath)
+		loader := NewLoader("testapp", "Test App", "", "nonexistent.yaml", "TESTAPP")
+
+		cfg, result, err := Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+		s.Require().Equal(7070, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, 7070)
+	})
+
+	s.Run("error", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: not-a-number\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+
+		cfg, result, err := Reload[testConfig](loader)
+		s.Require().Error(err, "expected error, got nil")
+		s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+		s.Require().Nil(cfg, "expected nil config")
+	})
+}
+
+func (s *LoaderSuite) TestLoadInto() {
+	// Mirrors the Config type of the simple example.
+	type exampleConfig struct {
+		Port     int    `mapstructure:"port"`
+		LogLevel string `mapstructure:"log_level"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	expected := exampleConfig{Port: 8080, LogLevel: "info"}
+	expected.DB.URL = "localhost:5432"
+	expected.DB.PoolSize = 10
+
+	testCases := []struct {
+		name           string
+		configPath     string
+		args           []string
+		expected       LoadResult
+		expectedConfig *exampleConfig
+		expectedError  error
+	}{
+		{
+			name:           "example config",
+			configPath:     filepath.Join("examples", "simple", "config.yaml"),
+			expected:       LoadResultContinue,
+			expectedConfig: &expected,
+		},
+		{
+			name:       "version",
+			configPath: filepath.Join("examples", "simple", "config.yaml"),
+			args:       []string{"--version"},
+			expected:   LoadResultStop,
+		},
+		{
+			name:          "error",
+			configPath:    "nonexistent.yaml",
+			expected:      LoadResultStop,
+			expectedError: errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			loader := NewLoader("simple-example", "Example", "", tC.configPath, "EXAMPLE")
+			os.Args = append([]string{"simple-example"}, tC.args...)
+			cfg, result, err := LoadInto[exampleConfig](loader, PlainVersionPrinter("v0.1.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().Error(err, "expected error, got nil")
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expectedConfig, cfg, "unexpected config: got %+v, want %+v", cfg, tC.expectedConfig)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_XDGDefaults() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		files         map[string]string // Relative path -> content.
+		xdgConfigHome string            // Relative to the temp dir. Empty means unset.
+		home          string            // Relative to the temp dir.
+		workDir       string            // Relative to the temp dir.
+		configPath    string            // Relative to the temp dir.
+		expectedPort  int
+		expectedError string
+	}{
+		{
+			name:          "found in XDG_CONFIG_HOME",
+			files:         map[string]string{"xdg/testapp/config.yaml": "port: 8080\n"},
+			xdgConfigHome: "xdg",
+			home:          "home",
+			workDir:       "work",
+			expectedPort:  8080,
+		},
+		{
+			name:         "XDG_CONFIG_HOME unset falls back to HOME",
+			files:        map[string]string{"home/.config/testapp/config.yaml": "port: 9090\n"},
+			home:         "home",
+			workDir:      "work",
+			expectedPort: 9090,
+		},
+		{
+			name: "XDG_CONFIG_HOME takes precedence over current directory",
+			files: map[string]string{
+				"xdg/testapp/config.yaml": "port: 8080\n",
+				"work/config.yaml":        "port: 7070\n",
+			},
+			xdgConfigHome: "xdg",
+			home:          "home",
+			workDir:       "work",
+			expectedPort:  8080,
+		},
+		{
+			name:          "found in current directory",
+			files:         map[string]string{"work/config.yaml": "port: 7070\n"},
+			xdgConfigHome: "xdg",
+			home:          "home",
+			workDir:       "work",
+			expectedPort:  7070,
+		},
+		{
+			name: "explicit config path takes precedence",
+			files: map[string]string{
+				"xdg/testapp/config.yaml": "port: 8080\n",
+				"custom.yaml":             "port: 6060\n",
+			},
+			xdgConfigHome: "xdg",
+			home:          "home",
+			workDir:       "work",
+			configPath:    "custom.yaml",
+			expectedPort:  6060,
+		},
+		{
+			name:          "not found",
+			xdgConfigHome: "xdg",
+			home:          "home",
+			workDir:       "work",
+			expectedError: `config file "config" not found in`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			dir := s.T().TempDir()
+			for name, content := range tC.files {
+				path := filepath.Join(dir, name)
+				s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o700), "create config dir")
+				s.Require().NoError(os.WriteFile(path, []byte(content), 0o600), "write config file")
+			}
+			s.Require().NoError(os.MkdirAll(filepath.Join(dir, tC.workDir), 0o700), "create work dir")
+			s.T().Chdir(filepath.Join(dir, tC.workDir))
+			s.T().Setenv("HOME", filepath.Join(dir, tC.home))
+			s.T().Setenv("XDG_CONFIG_HOME", "")
+			if tC.xdgConfigHome != "" {
+				s.T().Setenv("XDG_CONFIG_HOME", filepath.Join(dir, tC.xdgConfigHome))
+			}
+			configPath := ""
+			if tC.configPath != "" {
+				configPath = filepath.Join(dir, tC.configPath)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithXDGDefaults())
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_RequiredFields() {
+	type testConfig struct {
+		Port int `mapstructure:"port" required:"true"`
+		DB   struct {
+			URL      string `mapstructure:"url" required:"true"`
+			Password string `mapstructure:"password"`
+		} `mapstructure:"db"`
+	}
+
+	errCustom := errors.New("custom validation failed")
+
+	testCases := []struct {
+		name           string
+		content        string
+		opts           []Option
+		expected       LoadResult
+		expectedErrors []string
+		expectedError  error
+	}{
+		{
+			name:     "all required fields set",
+			content:  "port: 8080\ndb:\n  url: postgres://localhost\n",
+			expected: LoadResultContinue,
+		},
+		{
+			name:           "two required fields missing",
+			content:        "db:\n  password: secret\n",
+			expected:       LoadResultStop,
+			expectedErrors: []string{"'port' is required", "'db.url' is required"},
+		},
+		{
+			name:           "required and validator errors are joined",
+			content:        "port: 8080\n",
+			opts:           []Option{WithValidator(func(any) error { return errCustom })},
+			expected:       LoadResultStop,
+			expectedErrors: []string{"'db.url' is required", errCustom.Error()},
+			expectedError:  errCustom,
+		},
+		{
+			name:    "all validator errors are joined",
+			content: "port: 8080\ndb:\n  url: postgres://localhost\n",
+			opts: []Option{
+				WithValidator(func(any) error { return errCustom }),
+				WithValidator(func(any) error { return errSomeError }),
+			},
+			expected:       LoadResultStop,
+			expectedErrors: []string{errCustom.Error(), errSomeError.Error()},
+			expectedError:  errSomeError,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			if len(tC.expectedErrors) == 0 {
+				s.Require().NoError(err, "expected nil, got error")
+				return
+			}
+			for _, expected := range tC.expectedErrors {
+				s.Require().ErrorContains(err, expected, "unexpected error")
+			}
+			if tC.expectedError != nil {
+				s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_TrimSpace() {
+	type testConfig struct {
+		Password string   `mapstructure:"password"`
+		Banner   string   `mapstructure:"banner" trim:"false"`
+		Hosts    []string `mapstructure:"hosts"`
+		Token    *string  `mapstructure:"token"`
+		DB       struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+		Ups map[string]struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"ups"`
+		Tags map[string]string `mapstructure:"tags"`
+	}
+
+	testCases := []struct {
+		name             string
+		opts             []Option
+		env              map[string]string
+		expectedPassword string
+		expectedBanner   string
+		expectedHosts    []string
+		expectedToken    string
+		expectedURL      string
+		expectedUpURL    string
+		expectedTag      string
+	}{
+		{
+			name:             "trimmed",
+			opts:             []Option{WithTrimSpace()},
+			env:              map[string]string{"TESTAPP_PASSWORD": " secret\n", "TESTAPP_DB_URL": "\tpostgres://localhost \n"},
+			expectedPassword: "secret",
+			expectedBanner:   "  hello  ",
+			expectedHosts:    []string{"a", "b"},
+			expectedToken:    "token",
+			expectedURL:      "postgres://localhost",
+			expectedUpURL:    "u",
+			expectedTag:      "v",
+		},
+		{
+			name:             "not trimmed by default",
+			env:              map[string]string{"TESTAPP_PASSWORD": " secret\n", "TESTAPP_DB_URL": "\tpostgres://localhost \n"},
+			expectedPassword: " secret\n",
+			expectedBanner:   "  hello  ",
+			expectedHosts:    []string{" a ", "b\n"},
+			expectedToken:    " token ",
+			expectedURL:      "\tpostgres://localhost \n",
+			expectedUpURL:    " u ",
+			expectedTag:      " v ",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			content := "banner: \"  hello  \"\nhosts: [\" a \", \"b\\n\"]\ntoken: \" token \"\n" +
+				"ups:\n  x:\n    url: \" u \"\ntags:\n  k: \" v \"\n"
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedPassword, cfg.Password, "unexpected password")
+			s.Require().Equal(tC.expectedBanner, cfg.Banner, "unexpected banner")
+			s.Require().Equal(tC.expectedHosts, cfg.Hosts, "unexpected hosts")
+			s.Require().NotNil(cfg.Token, "expected token, got nil")
+			s.Require().Equal(tC.expectedToken, *cfg.Token, "unexpected token")
+			s.Require().Equal(tC.expectedURL, cfg.DB.URL, "unexpected db url")
+			s.Require().Equal(tC.expectedUpURL, cfg.Ups["x"].URL, "unexpected map entry url")
+			s.Require().Equal(tC.expectedTag, cfg.Tags["k"], "unexpected map value")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Defaults() {
+	type upstreamConfig struct {
+		URL     string        `mapstructure:"url" required:"true"`
+		Timeout time.Duration `mapstructure:"timeout" default:"5s"`
+		Retries int           `mapstructure:"retries" default:"3"`
+		TLS     struct {
+			Enabled bool `mapstructure:"enabled" default:"true"`
+		} `mapstructure:"tls"`
+	}
+	type testConfig struct {
+		Port      int                       `mapstructure:"port" default:"8080"`
+		Host      string                    `mapstructure:"host" default:"localhost"`
+		Upstreams map[string]upstreamConfig `mapstructure:"upstreams"`
+	}
+	upstream := func(url string, timeout time.Duration, tls bool) upstreamConfig {
+		u := upstreamConfig{URL: url, Timeout: timeout, Retries: 3}
+		u.TLS.Enabled = tls
+		return u
+	}
+
+	testCases := []struct {
+		name          string
+		content       string
+		env           map[string]string
+		expected      *testConfig
+		expectedError string
+	}{
+		{
+			name:    "defaults applied",
+			content: "{}\n",
+			expected: &testConfig{
+				Port: 8080,
+				Host: "localhost",
+			},
+		},
+		{
+			name:    "file and env override defaults",
+			content: "port: 9090\n",
+			env:     map[string]string{"TESTAPP_HOST": "example.com"},
+			expected: &testConfig{
+				Port: 9090,
+				Host: "example.com",
+			},
+		},
+		{
+			name: "nested defaults applied per map entry",
+			content: `
+upstreams:
+  auth:
+    url: http://auth
+  billing:
+    url: http://billing
+    timeout: 10s
+    tls:
+      enabled: false
+`,
+			expected: &testConfig{
+				Port: 8080,
+				Host: "localhost",
+				Upstreams: map[string]upstreamConfig{
+					"auth":    upstream("http://auth", 5*time.Second, true),
+					"billing": upstream("http://billing", 10*time.Second, false),
+				},
+			},
+		},
+		{
+			name:          "required checked per map entry",
+			content:       "upstreams:\n  auth:\n    timeout: 1s\n",
+			expectedError: "'upstreams.auth.url' is required",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, cfg, "unexpected config: got %+v, want %+v", cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvPrefixFlag() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	testCases := []struct {
+		name          string
+		opts          []Option
+		args          []string
+		env           map[string]string
+		otherConfig   bool // Pass the other config file via OTHER_CONFIG.
+		expectedPort  int
+		expectedHost  string
+		expectedError bool
+	}{
+		{
+			name:         "default prefix",
+			opts:         []Option{WithEnvPrefixFlag()},
+			env:          map[string]string{"TESTAPP_PORT": "7070", "OTHER_PORT": "9090"},
+			expectedPort: 7070,
+			expectedHost: "localhost",
+		},
+		{
+			name:         "overridden prefix",
+			opts:         []Option{WithEnvPrefixFlag()},
+			args:         []string{"--env-prefix", "OTHER"},
+			env:          map[string]string{"TESTAPP_PORT": "7070", "OTHER_PORT": "9090"},
+			expectedPort: 9090,
+			expectedHost: "localhost",
+		},
+		{
+			name:         "overridden prefix applies to env bindings",
+			opts:         []Option{WithEnvPrefixFlag(), WithEnvBindings("host")},
+			args:         []string{"--env-prefix", "OTHER"},
+			env:          map[string]string{"TESTAPP_HOST": "testapp.local", "OTHER_HOST": "other.local"},
+			expectedPort: 8080,
+			expectedHost: "other.local",
+		},
+		{
+			name:         "overridden prefix applies to config path",
+			opts:         []Option{WithEnvPrefixFlag()},
+			args:         []string{"--env-prefix", "OTHER"},
+			otherConfig:  true,
+			expectedPort: 6060,
+			expectedHost: "localhost",
+		},
+		{
+			name:          "flag disabled by default",
+			args:          []string{"--env-prefix", "OTHER"},
+			expectedError: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\n"))
+			if tC.otherConfig {
+				s.T().Setenv("OTHER_CONFIG", s.writeConfigFile("other.yaml", []byte("port: 6060\nhost: localhost\n")))
+			}
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			os.Args = append([]string{"testapp"}, tC.args...)
+			cfg := &testConfig{}
+			_, result, err := loader.LoadWithViper(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError {
+				s.Require().Error(err, "expected error, got nil")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+			s.Require().Equal(tC.expectedHost, cfg.Host, "unexpected host: got %q, want %q", cfg.Host, tC.expectedHost)
+		})
+	}
+
+	s.Run("key sources", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\n"))
+		s.T().Setenv("OTHER_PORT", "9090")
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithEnvPrefixFlag())
+		os.Args = []string{"testapp", "--env-prefix", "OTHER"}
+		v, _, err := loader.LoadWithViper(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		sources := loader.KeySources(v)
+		s.Require().Equal(KeySourceEnv, sources["port"], "unexpected port source")
+		s.Require().Equal(KeySourceFlag, sources["env-prefix"], "unexpected env-prefix source")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_ValidateCommand() {
+	type testConfig struct {
+		Port int    `mapstructure:"port" required:"true"`
+		Host string `mapstructure:"host" required:"true"`
+	}
+
+	testCases := []struct {
+		name           string
+		args           []string // "{dir}" is replaced with the temp dir.
+		expected       LoadResult
+		expectedOutput string
+		expectedErrors []string
+	}{
+		{
+			name:           "valid file",
+			args:           []string{"config", "validate", "{dir}/valid.yaml"},
+			expected:       LoadResultCommand,
+			expectedOutput: "OK\n",
+		},
+		{
+			name:           "invalid file",
+			args:           []string{"config", "validate", "{dir}/invalid.yaml"},
+			expected:       LoadResultStop,
+			expectedErrors: []string{"'port' is required", "'host' is required"},
+		},
+		{
+			name:           "malformed file",
+			args:           []string{"config", "validate", "{dir}/malformed.yaml"},
+			expected:       LoadResultStop,
+			expectedErrors: []string{"malformed.yaml"},
+		},
+		{
+			name:           "missing file",
+			args:           []string{"config", "validate", "{dir}/missing.yaml"},
+			expected:       LoadResultStop,
+			expectedErrors: []string{"missing.yaml"},
+		},
+		{
+			name:           "configured file by default",
+			args:           []string{"config", "validate"},
+			expected:       LoadResultStop,
+			expectedErrors: []string{"'host' is required"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			// The configured file is invalid, so it must not be loaded when validating the other one.
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			dir := filepath.Dir(configPath)
+			files := map[string]string{
+				"valid.yaml":     "port: 8080\nhost: localhost\n",
+				"invalid.yaml":   "{}\n",
+				"malformed.yaml": "port: [\n",
+			}
+			for name, content := range files {
+				s.Require().NoError(os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600), "write config file")
+			}
+			args := make([]string, 0, len(tC.args))
+			for _, arg := range tC.args {
+				args = append(args, strings.ReplaceAll(arg, "{dir}", dir))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithValidateCommand(), WithDetailedResults())
+			cfg := &testConfig{}
+			output := &bytes.Buffer{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), output)
+
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(testConfig{}, *cfg, "config must be left intact")
+			if len(tC.expectedErrors) == 0 {
+				s.Require().NoError(err, "expected nil, got error")
+				s.Require().Equal(tC.expectedOutput, output.String(), "unexpected output")
+				return
+			}
+			for _, expected := range tC.expectedErrors {
+				s.Require().ErrorContains(err, expected, "unexpected error")
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigDir() {
+	type testConfig struct {
+		Port     int    `mapstructure:"port"`
+		Host     string `mapstructure:"host"`
+		LogLevel string `mapstructure:"log_level"`
+	}
+
+	testCases := []struct {
+		name          string
+		files         map[string]string // Relative to the config dir. Nil means missing dir.
+		overlay       string
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name: "later file wins",
+			files: map[string]string{
+				"10-override.yaml": "port: 9090\n",
+				"00-base.yaml":     "port: 7070\nhost: base.local\n",
+			},
+			expected: testConfig{Port: 9090, Host: "base.local", LogLevel: "info"},
+		},
+		{
+			name: "mixed formats",
+			files: map[string]string{
+				"00-base.json":     `{"port": 7070, "host": "base.local"}`,
+				"10-override.toml": "host = \"override.local\"\n",
+			},
+			expected: testConfig{Port: 7070, Host: "override.local", LogLevel: "info"},
+		},
+		{
+			name: "non-config files and subdirectories skipped",
+			files: map[string]string{
+				"00-base.yaml":       "port: 7070\n",
+				"README.md":          "# not a config\n",
+				"20-backup.yaml.bak": "port: [\n",
+				"sub/30-nested.yaml": "port: 6060\n",
+			},
+			expected: testConfig{Port: 7070, Host: "localhost", LogLevel: "info"},
+		},
+		{
+			name:     "empty dir",
+			files:    map[string]string{},
+			expected: testConfig{Port: 8080, Host: "localhost", LogLevel: "info"},
+		},
+		{
+			name:     "additional config file on top",
+			files:    map[string]string{"00-base.yaml": "port: 7070\nlog_level: debug\n"},
+			overlay:  "log_level: warn\n",
+			expected: testConfig{Port: 7070, Host: "localhost", LogLevel: "warn"},
+		},
+		{
+			name:          "malformed file",
+			files:         map[string]string{"00-base.yaml": "port: [\n"},
+			expectedError: "00-base.yaml",
+		},
+		{
+			name:          "missing dir",
+			expectedError: "read config dir",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\nlog_level: info\n"))
+			dir := filepath.Join(s.T().TempDir(), "conf.d")
+			if tC.files != nil {
+				s.Require().NoError(os.MkdirAll(dir, 0o700), "create config dir")
+			}
+			for name, content := range tC.files {
+				path := filepath.Join(dir, name)
+				s.Require().NoError(os.MkdirAll(filepath.Dir(path), 0o700), "create config dir")
+				s.Require().NoError(os.WriteFile(path, []byte(content), 0o600), "write config file")
+			}
+			opts := []Option{WithConfigDir(dir)}
+			if tC.overlay != "" {
+				opts = append(opts, WithAdditionalConfigFiles(s.writeConfigFile("overlay.yaml", []byte(tC.overlay))))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadValues() {
+	type testConfig struct {
+		DB struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+	}
+
+	content := `
+db:
+  url: postgres://localhost
+  pool_size: 10
+  timeout: 5s
+plugin:
+  hosts: [a, b]
+`
+	configPath := s.writeConfigFile("config.yaml", []byte(content))
+	s.T().Setenv("TESTAPP_DB_URL", "postgres://override")
+
+	loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+	os.Args = []string{"testapp"}
+	values, result, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+	s.Require().NoError(err, "expected nil, got error")
+	s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+
+	s.Require().Equal(10, values.GetInt("db.pool_size"), "unexpected pool size")
+	s.Require().Equal("postgres://override", values.GetString("db.url"), "env must take precedence")
+	s.Require().Equal(5*time.Second, values.GetDuration("db.timeout"), "unexpected timeout")
+	s.Require().Equal([]string{"a", "b"}, values.GetStringSlice("plugin.hosts"), "unexpected hosts")
+	s.Require().True(values.IsSet("db.pool_size"), "pool size must be set")
+	s.Require().False(values.IsSet("db.missing"), "missing key must not be set")
+	s.Require().Zero(values.GetInt("db.missing"), "missing key must be zero")
+
+	s.Run("explicitly set keys", func() {
+		type testConfig struct {
+			Port     int    `mapstructure:"port"`
+			Host     string `mapstructure:"host" default:"localhost"`
+			LogLevel string `mapstructure:"log_level"`
+			Timeout  string `mapstructure:"timeout"`
+		}
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		s.T().Setenv("TESTAPP_LOG_LEVEL", "debug")
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		os.Args = []string{"testapp"}
+		values, _, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		s.Require().True(values.FieldWasSet("port"), "key from file must be set")
+		s.Require().True(values.FieldWasSet("LOG_LEVEL"), "key from env must be set, case-insensitively")
+		s.Require().False(values.FieldWasSet("host"), "key with default value must not be set")
+		s.Require().True(values.IsSet("host"), "key with default value is reported by IsSet")
+		s.Require().False(values.FieldWasSet("timeout"), "unset key must not be set")
+		s.Require().False(values.FieldWasSet("config"), "built-in key must not be reported")
+		s.Require().Equal([]string{"log_level", "port"}, values.SetKeys(), "unexpected set keys")
+	})
+
+	s.Run("error", func() {
+		loader := NewLoader("testapp", "Test App", "", "nonexistent.yaml", "TESTAPP")
+		values, _, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().Error(err, "expected error, got nil")
+		s.Require().Nil(values, "values must be nil on error")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_TagName() {
+	type jsonConfig struct {
+		LogLevel string `json:"log_level,omitempty"`
+		DB       struct {
+			URL      string `json:"url"`
+			PoolSize int    `json:"pool_size" default:"5"`
+		} `json:"db"`
+	}
+	type yamlConfig struct {
+		LogLevel string `yaml:"log_level"`
+		DB       struct {
+			URL string `yaml:"url"`
+		} `yaml:"db"`
+	}
+
+	content := "log_level: debug\ndb:\n  url: postgres://localhost\n"
+
+	s.Run("json tags", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		s.T().Setenv("TESTAPP_DB_URL", "postgres://override")
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithTagName("json"), WithStrictKeys())
+		cfg := &jsonConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("debug", cfg.LogLevel, "unexpected log level")
+		s.Require().Equal("postgres://override", cfg.DB.URL, "env must be bound by the json tag")
+		s.Require().Equal(5, cfg.DB.PoolSize, "default must be applied by the json tag")
+	})
+
+	s.Run("yaml tags", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithTagName("yaml"))
+		cfg := &yamlConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("debug", cfg.LogLevel, "unexpected log level")
+		s.Require().Equal("postgres://localhost", cfg.DB.URL, "unexpected db url")
+	})
+
+	s.Run("mapstructure by default", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &jsonConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Empty(cfg.LogLevel, "json tags must be ignored by default")
+	})
+}
+
+func (s *LoaderSuite) TestShouldExit() {
+	testCases := []struct {
+		name         string
+		result       LoadResult
+		err          error
+		expectedCode int
+		expectedExit bool
+	}{
+		{name: "continue", result: LoadResultContinue, expectedCode: 0, expectedExit: false},
+		{name: "stop", result: LoadResultStop, expectedCode: 0, expectedExit: true},
+		{name: "help", result: LoadResultHelp, expectedCode: 0, expectedExit: true},
+		{name: "version", result: LoadResultVersion, expectedCode: 0, expectedExit: true},
+		{name: "check config", result: LoadResultCheckConfig, expectedCode: 0, expectedExit: true},
+		{name: "command", result: LoadResultCommand, expectedCode: 0, expectedExit: true},
+		{name: "print config", result: LoadResultPrintConfig, expectedCode: 0, expectedExit: true},
+		{name: "error with stop", result: LoadResultStop, err: errSomeError, expectedCode: 1, expectedExit: true},
+		{name: "error with continue", result: LoadResultContinue, err: errSomeError, expectedCode: 1, expectedExit: true},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			code, exit := ShouldExit(tC.result, tC.err)
+			s.Require().Equal(tC.expectedCode, code, "unexpected exit code: got %d, want %d", code, tC.expectedCode)
+			s.Require().Equal(tC.expectedExit, exit, "unexpected exit flag: got %v, want %v", exit, tC.expectedExit)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadResult_String() {
+	testCases := []struct {
+		result   LoadResult
+		expected string
+	}{
+		{result: LoadResultContinue, expected: "continue"},
+		{result: LoadResultStop, expected: "stop"},
+		{result: LoadResultHelp, expected: "help"},
+		{result: LoadResultVersion, expected: "version"},
+		{result: LoadResultCheckConfig, expected: "check-config"},
+		{result: LoadResultCommand, expected: "command"},
+		{result: LoadResultPrintConfig, expected: "print-config"},
+		{result: LoadResult(42), expected: "LoadResult(42)"},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.expected, func() {
+			s.Require().Equal(tC.expected, tC.result.String(), "unexpected string")
+			s.Require().Equal(tC.expected, fmt.Sprint(tC.result), "unexpected formatted value")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigB64() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	encode := func(data string) string {
+		return base64.StdEncoding.EncodeToString([]byte(data))
+	}
+	long := encode("port: 9090\nhost: a-rather-long-host-name.example.com\n")
+
+	testCases := []struct {
+		name          string
+		configPath    string // Relative to the temp dir. Written if content is set.
+		content       string
+		encoded       string
+		flagContent   string // Written to a file, passed by --config, if set.
+		env           map[string]string
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:       "yaml blob replaces missing config file",
+			configPath: "config.yaml",
+			encoded:    encode("port: 9090\nhost: b64.local\n"),
+			expected:   testConfig{Port: 9090, Host: "b64.local"},
+		},
+		{
+			name:       "config file not read",
+			configPath: "config.yaml",
+			content:    "port: 8080\nhost: file.local\n",
+			encoded:    encode("port: 9090\n"),
+			expected:   testConfig{Port: 9090},
+		},
+		{
+			name:       "format inferred from config path",
+			configPath: "config.toml",
+			encoded:    encode("port = 9090\nhost = \"b64.local\"\n"),
+			expected:   testConfig{Port: 9090, Host: "b64.local"},
+		},
+		{
+			name:     "yaml assumed without config path",
+			encoded:  encode("port: 9090\n"),
+			expected: testConfig{Port: 9090},
+		},
+		{
+			name:       "wrapped base64",
+			configPath: "config.yaml",
+			encoded:    long[:20] + "\n" + long[20:],
+			expected:   testConfig{Port: 9090, Host: "a-rather-long-host-name.example.com"},
+		},
+		{
+			name:       "env overrides blob",
+			configPath: "config.yaml",
+			encoded:    encode("port: 9090\nhost: b64.local\n"),
+			env:        map[string]string{"TESTAPP_PORT": "7070"},
+			expected:   testConfig{Port: 7070, Host: "b64.local"},
+		},
+		{
+			name:        "config flag wins over blob",
+			configPath:  "config.yaml",
+			encoded:     encode("port: 9090\nhost: b64.local\n"),
+			flagContent: "port: 1111\n",
+			expected:    testConfig{Port: 1111},
+		},
+		{
+			name:          "invalid base64",
+			configPath:    "config.yaml",
+			encoded:       "not base64!",
+			expectedError: "decode TESTAPP_CONFIG_B64",
+		},
+		{
+			name:          "malformed config",
+			configPath:    "config.yaml",
+			encoded:       encode("port: [\n"),
+			expectedError: "TESTAPP_CONFIG_B64",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := ""
+			if tC.configPath != "" {
+				configPath = filepath.Join(s.T().TempDir(), tC.configPath)
+			}
+			if tC.content != "" {
+				s.Require().NoError(os.WriteFile(configPath, []byte(tC.content), 0o600), "write config file")
+			}
+			s.T().Setenv("TESTAPP_CONFIG_B64", tC.encoded)
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			var args []string
+			if tC.flagContent != "" {
+				flagPath := filepath.Join(s.T().TempDir(), "flag.yaml")
+				s.Require().NoError(os.WriteFile(flagPath, []byte(tC.flagContent), 0o600), "write flag config file")
+				args = []string{"--config", flagPath}
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvExpansion() {
+	type testConfig struct {
+		URL      string   `mapstructure:"url"`
+		Home     string   `mapstructure:"home"`
+		Literal  string   `mapstructure:"literal"`
+		Escaped  string   `mapstructure:"escaped"`
+		Password string   `mapstructure:"password"`
+		Hosts    []string `mapstructure:"hosts"`
+		Port     int      `mapstructure:"port"`
+	}
+
+	content := `
+url: "postgres://${DB_HOST}:5432/app"
+home: "${HOME}"
+literal: "$$LITERAL"
+escaped: "$${DB_HOST}"
+password: "pa$word"
+hosts: ["${DB_HOST}", "static"]
+port: 8080
+`
+
+	testCases := []struct {
+		name          string
+		content       string
+		opts          []Option
+		env           map[string]string
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:    "expanded",
+			content: content,
+			opts:    []Option{WithEnvExpansion()},
+			env:     map[string]string{"DB_HOST": "db.local", "HOME": "/home/test"},
+			expected: testConfig{
+				URL:      "postgres://db.local:5432/app",
+				Home:     "/home/test",
+				Literal:  "$$LITERAL",
+				Escaped:  "${DB_HOST}",
+				Password: "pa$word",
+				Hosts:    []string{"db.local", "static"},
+				Port:     8080,
+			},
+		},
+		{
+			name:    "empty variable",
+			content: "url: \"postgres://${DB_HOST}:5432/app\"\n",
+			opts:    []Option{WithEnvExpansion()},
+			env:     map[string]string{"DB_HOST": ""},
+			expected: testConfig{
+				URL: "postgres://:5432/app",
+			},
+		},
+		{
+			name:    "env values not expanded",
+			content: "url: \"postgres://${DB_HOST}:5432/app\"\n",
+			opts:    []Option{WithEnvExpansion()},
+			env:     map[string]string{"DB_HOST": "db.local", "TESTAPP_URL": "${DB_HOST}"},
+			expected: testConfig{
+				URL: "${DB_HOST}",
+			},
+		},
+		{
+			name:    "disabled by default",
+			content: "url: \"postgres://${DB_HOST}:5432/app\"\n",
+			env:     map[string]string{"DB_HOST": "db.local"},
+			expected: testConfig{
+				URL: "postgres://${DB_HOST}:5432/app",
+			},
+		},
+		{
+			name:          "unset variable",
+			content:       "url: \"postgres://${CONFIGKIT_UNSET_HOST}:5432/app\"\n",
+			opts:          []Option{WithEnvExpansion()},
+			expectedError: `'url' expand env: env variable "CONFIGKIT_UNSET_HOST" is not set`,
+		},
+		{
+			name:          "unterminated reference",
+			content:       "url: \"postgres://${DB_HOST:5432/app\"\n",
+			opts:          []Option{WithEnvExpansion()},
+			expectedError: "unterminated",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadFromMap() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+		DB   struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+	}
+
+	s.T().Setenv("TESTAPP_PORT", "7070")
+	loader := NewLoader("testapp", "Test App", "", "", "TESTAPP")
+	cfg := &testConfig{}
+	result, err := loader.LoadFromMap(cfg, map[string]any{
+		"port": 8080,
+		"db":   map[string]any{"url": "postgres://localhost"},
+	})
+
+	s.Require().NoError(err, "expected nil, got error")
+	s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+	s.Require().Equal(7070, cfg.Port, "env must take precedence")
+	s.Require().Equal("postgres://localhost", cfg.DB.URL, "unexpected db url")
+
+	_, err = loader.LoadFromMap(testConfig{}, nil)
+	s.Require().Error(err, "expected error, got nil")
+}
+
+func (s *LoaderSuite) TestLoadArgs() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name         string
+		args         []string
+		expected     LoadResult
+		expectedPort int
+	}{
+		{
+			name:         "no args",
+			expected:     LoadResultContinue,
+			expectedPort: 8080,
+		},
+		{
+			name:         "config flag",
+			args:         []string{"--config", "override.yaml"},
+			expected:     LoadResultContinue,
+			expectedPort: 9090,
+		},
+		{
+			name:     "version flag",
+			args:     []string{"--version"},
+			expected: LoadResultStop,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			overridePath := filepath.Join(filepath.Dir(configPath), "override.yaml")
+			s.Require().NoError(os.WriteFile(overridePath, []byte("port: 9090\n"), 0o600), "write config file")
+			args := slices.Clone(tC.args)
+			if len(args) > 1 && args[0] == "--config" {
+				args[1] = overridePath
+			}
+
+			// os.Args must be neither parsed nor modified.
+			osArgs := []string{"testapp", "--unknown-flag"}
+			os.Args = osArgs
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+			s.Require().Equal(osArgs, os.Args, "os.Args must be left untouched")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_DeprecatedKeys() {
+	type testConfig struct {
+		DB struct {
+			Host string `mapstructure:"host"`
+		} `mapstructure:"db"`
+		LogLevel string `mapstructure:"log_level"`
+	}
+
+	deprecated := map[string]string{
+		"db.host_name": "db.host",
+		"verbosity":    "log_level",
+	}
+
+	testCases := []struct {
+		name             string
+		content          string
+		env              map[string]string
+		expectedHost     string
+		expectedLogLevel string
+		expectedWarnings []string
+	}{
+		{
+			name:             "new keys",
+			content:          "db:\n  host: new.local\nlog_level: info\n",
+			expectedHost:     "new.local",
+			expectedLogLevel: "info",
+		},
+		{
+			name:             "deprecated keys aliased",
+			content:          "db:\n  host_name: old.local\nverbosity: debug\n",
+			expectedHost:     "old.local",
+			expectedLogLevel: "debug",
+			expectedWarnings: []string{
+				`config key \"db.host_name\" is deprecated, use \"db.host\" instead`,
+				`config key \"verbosity\" is deprecated, use \"log_level\" instead`,
+			},
+		},
+		{
+			name:             "deprecated key from env",
+			content:          "{}\n",
+			env:              map[string]string{"TESTAPP_VERBOSITY": "warn"},
+			expectedLogLevel: "warn",
+			expectedWarnings: []string{`config key \"verbosity\" is deprecated, use \"log_level\" instead`},
+		},
+		{
+			name:             "both set, new wins",
+			content:          "db:\n  host: new.local\n  host_name: old.local\n",
+			expectedHost:     "new.local",
+			expectedWarnings: []string{`config key \"db.host_name\" is deprecated and ignored, as its replacement \"db.host\" is set`},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(buf, nil))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+				WithDeprecatedKeys(deprecated), WithStrictKeys(), WithLogger(logger))
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expectedHost, cfg.DB.Host, "unexpected host")
+			s.Require().Equal(tC.expectedLogLevel, cfg.LogLevel, "unexpected log level")
+			for _, warning := range tC.expectedWarnings {
+				s.Require().Contains(buf.String(), "level=WARN msg=\""+warning+"\"", "expected warning")
+			}
+			if len(tC.expectedWarnings) == 0 {
+				s.Require().NotContains(buf.String(), "deprecated", "unexpected warning")
+			}
+		})
+	}
+
+	s.Run("warning to the writer without logger", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("verbosity: debug\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithDeprecatedKeys(deprecated))
+		buf := &bytes.Buffer{}
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), buf)
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("debug", cfg.LogLevel, "unexpected log level")
+		s.Require().Equal("Warning: config key \"verbosity\" is deprecated, use \"log_level\" instead\n", buf.String(),
+			"warning must be written to the writer of the load")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_OneOfGroups() {
+	type testConfig struct {
+		Auth struct {
+			Token    string `mapstructure:"token" oneof:"auth"`
+			User     string `mapstructure:"user" oneof:"auth:basic"`
+			Password string `mapstructure:"password" oneof:"auth:basic"`
+		} `mapstructure:"auth"`
+		Output struct {
+			File   string `mapstructure:"file" oneof:"output"`
+			Stdout bool   `mapstructure:"stdout" oneof:"output"`
+		} `mapstructure:"output"`
+		Upstreams map[string]struct {
+			Token string `mapstructure:"token" oneof:"auth"`
+			User  string `mapstructure:"user" oneof:"auth"`
+		} `mapstructure:"ups"`
+	}
+
+	testCases := []struct {
+		name           string
+		content        string
+		expectedErrors []string
+	}{
+		{
+			name:    "map entries checked separately",
+			content: "auth:\n  token: secret\noutput:\n  stdout: true\nups:\n  a:\n    token: t1\n  b:\n    token: t2\n",
+		},
+		{
+			name: "map entry invalid",
+			content: "auth:\n  token: secret\noutput:\n  stdout: true\n" +
+				"ups:\n  a:\n    token: t1\n  b:\n    token: t2\n    user: admin\n",
+			expectedErrors: []string{
+				`oneof group "auth": exactly one of ups.b.token, ups.b.user must be set, got ups.b.token, ups.b.user`,
+			},
+		},
+		{
+			name:    "token set",
+			content: "auth:\n  token: secret\noutput:\n  stdout: true\n",
+		},
+		{
+			name:    "user and password set",
+			content: "auth:\n  user: admin\n  password: secret\noutput:\n  file: out.log\n",
+		},
+		{
+			name:    "none set",
+			content: "output:\n  stdout: true\n",
+			expectedErrors: []string{
+				`oneof group "auth": exactly one of auth.token, auth.user+auth.password must be set, got none`,
+			},
+		},
+		{
+			name:    "multiple set",
+			content: "auth:\n  token: secret\n  user: admin\n  password: secret\noutput:\n  file: out.log\n  stdout: true\n",
+			expectedErrors: []string{
+				`oneof group "auth": exactly one of auth.token, auth.user+auth.password must be set, got auth.token, auth.user+auth.password`,
+				`oneof group "output": exactly one of output.file, output.stdout must be set, got output.file, output.stdout`,
+			},
+		},
+		{
+			name:    "alternative set partially",
+			content: "auth:\n  user: admin\noutput:\n  stdout: true\n",
+			expectedErrors: []string{
+				`oneof group "auth": auth.user+auth.password must be set together, missing: auth.password`,
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			result, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if len(tC.expectedErrors) == 0 {
+				s.Require().NoError(err, "expected nil, got error")
+				s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+				return
+			}
+			s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+			for _, expected := range tC.expectedErrors {
+				s.Require().ErrorContains(err, expected, "unexpected error")
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_FileCache() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	s.Run("unchanged file read once", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithFileCache())
+
+		for range 2 {
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(testConfig{Port: 8080, Host: "localhost"}, *cfg, "unexpected config")
+		}
+		s.Require().EqualValues(1, loader.fileCache.reads.Load(), "file must be read once")
+
+		// Changed file is read again.
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: 9090\n"), 0o600), "write config file")
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(testConfig{Port: 9090}, *cfg, "unexpected config")
+		s.Require().EqualValues(2, loader.fileCache.reads.Load(), "changed file must be read again")
+	})
+
+	s.Run("cached settings are not shared", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithFileCache())
+
+		s.T().Setenv("TESTAPP_HOST", "env.local")
+		cfg, _, err := Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("env.local", cfg.Host, "env must take precedence")
+
+		s.T().Setenv("TESTAPP_HOST", "")
+		cfg, _, err = Reload[testConfig](loader)
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("localhost", cfg.Host, "cached value must be intact")
+	})
+
+	s.Run("concurrent loads", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithFileCache())
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 8)
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cfg, _, err := Reload[testConfig](loader)
+				if err == nil && cfg.Port != 8080 {
+					err = fmt.Errorf("unexpected port %d", cfg.Port)
+				}
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			s.Require().NoError(err, "expected nil, got error")
+		}
+	})
+
+	s.Run("malformed file", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: [\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithFileCache())
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		var parseErr *ConfigParseError
+		s.Require().ErrorAs(err, &parseErr, "expected parse error")
+		s.Require().Equal(configPath, parseErr.Path, "unexpected path")
+	})
+
+	s.Run("missing file", func() {
+		loader := NewLoader("testapp", "Test App", "", filepath.Join(s.T().TempDir(), "missing.yaml"), "TESTAPP",
+			WithFileCache(), WithOptionalConfigFile())
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "optional missing file must be skipped")
+	})
+}
+
+func BenchmarkLoad_FileCache(b *testing.B) {
+	type benchConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+		DB   struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	configPath := filepath.Join(b.TempDir(), "config.yaml")
+	content := "port: 8080\nhost: localhost\ndb:\n  url: postgres://localhost\n  pool_size: 10\n"
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		b.Fatalf("write config file: %v", err)
+	}
+
+	for _, bC := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "uncached"},
+		{name: "cached", opts: []Option{WithFileCache()}},
+	} {
+		b.Run(bC.name, func(b *testing.B) {
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", bC.opts...)
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, _, err := Reload[benchConfig](loader); err != nil {
+					b.Fatalf("reload: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// benchLargeConfig is a config with many nested fields and tags, to exercise the reflection walks.
+type benchLargeConfig struct {
+	Server struct {
+		Host         string        `mapstructure:"host" default:"localhost"`
+		Port         int           `mapstructure:"port" default:"8080" required:"true"`
+		ReadTimeout  time.Duration `mapstructure:"read_timeout" default:"5s"`
+		WriteTimeout time.Duration `mapstructure:"write_timeout" default:"5s"`
+		TLS          struct {
+			Enabled  bool   `mapstructure:"enabled"`
+			CertPath string `mapstructure:"cert_path"`
+			KeyPath  string `mapstructure:"key_path" secret:"true"`
+		} `mapstructure:"tls"`
+	} `mapstructure:"server"`
+	DB struct {
+		URL      string        `mapstructure:"url" required:"true"`
+		Password string        `mapstructure:"password" secret:"true"`
+		PoolSize int           `mapstructure:"pool_size" default:"10"`
+		Timeout  time.Duration `mapstructure:"timeout" default:"1s"`
+		Replica  struct {
+			URL     string `mapstructure:"url"`
+			Enabled bool   `mapstructure:"enabled"`
+		} `mapstructure:"replica"`
+	} `mapstructure:"db"`
+	Log struct {
+		Level  string `mapstructure:"level" default:"info"`
+		Format string `mapstructure:"format" default:"json"`
+		Output string `mapstructure:"output" env:"BENCH_LOG_OUTPUT"`
+	} `mapstructure:"log"`
+	Features  []string                    `mapstructure:"features"`
+	Upstreams map[string]benchUpstream    `mapstructure:"upstreams"`
+	Started   time.Time                   `mapstructure:"started" timeformat:"2006-01-02"`
+	Labels    map[string]string           `mapstructure:"labels"`
+	Limits    map[string]int              `mapstructure:"limits"`
+	Extra     struct{ A, B, C, D string } `mapstructure:"extra"`
+}
+
+// benchUpstream is a map entry of benchLargeConfig.
+type benchUpstream struct {
+	URL     string        `mapstructure:"url" required:"true"`
+	Timeout time.Duration `mapstructure:"timeout" default:"2s"`
+	Retries int           `mapstructure:"retries" default:"3"`
+}
+
+func BenchmarkLoad(b *testing.B) {
+	configPath := filepath.Join(b.TempDir(), "config.yaml")
+	content := `
+server:
+  port: 9090
+db:
+  url: postgres://localhost
+  password: secret
+upstreams:
+  auth:
+    url: http://auth
+  billing:
+    url: http://billing
+started: 2024-01-05
+features: [a, b, c]
+`
+	if err := os.WriteFile(configPath, []byte(content), 0o600); err != nil {
+		b.Fatalf("write config file: %v", err)
+	}
+
+	loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithTrimSpace())
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := loader.LoadArgs(nil, &benchLargeConfig{}, PlainVersionPrinter("v1.0.0"), io.Discard); err != nil {
+			b.Fatalf("load: %v", err)
+		}
+	}
+}
+
+func (s *LoaderSuite) TestStructKeys_Cached() {
+	type nested struct {
+		URL string `mapstructure:"url" json:"address"`
+	}
+	type testConfig struct {
+		Port int    `mapstructure:"port" json:"listen_port"`
+		DB   nested `mapstructure:"db" json:"database"`
+	}
+
+	t := reflect.TypeOf(&testConfig{})
+	dotted := keyFormat{delim: ".", tag: "mapstructure"}
+	expected := []string{"port", "db.url"}
+	s.Require().Equal(expected, structKeys(t, dotted), "unexpected keys")
+	s.Require().Equal(expected, structKeys(t, dotted), "cached keys must be the same")
+
+	// Key formats are cached separately.
+	s.Require().Equal([]string{"port", "db::url"}, structKeys(t, keyFormat{delim: "::", tag: "mapstructure"}), "unexpected keys")
+	s.Require().Equal([]string{"listen_port", "database.address"}, structKeys(t, keyFormat{delim: ".", tag: "json"}), "unexpected keys")
+
+	var prefixed []string
+	walkStructKeys(t, "root.", dotted, func(key string, _ reflect.StructField) {
+		prefixed = append(prefixed, key)
+	})
+	s.Require().Equal([]string{"root.port", "root.db.url"}, prefixed, "prefix must be applied to cached keys")
+}
+
+func (s *LoaderSuite) TestLoad_MixedFormats() {
+	type dbConfig struct {
+		Host string `mapstructure:"host"`
+		Pool int    `mapstructure:"pool"`
+	}
+	type testConfig struct {
+		Port int      `mapstructure:"port"`
+		DB   dbConfig `mapstructure:"db"`
+	}
+
+	type file struct {
+		name    string
+		content string
+	}
+
+	testCases := []struct {
+		name          string
+		base          file
+		overlays      []file
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:     "yaml base and json overlay",
+			base:     file{"config.yaml", "port: 8080\ndb:\n  host: localhost\n  pool: 5\n"},
+			overlays: []file{{"override.json", `{"db": {"host": "db.prod"}}`}},
+			expected: testConfig{Port: 8080, DB: dbConfig{Host: "db.prod", Pool: 5}},
+		},
+		{
+			name: "json base and yaml, toml, jsonc overlays",
+			base: file{"config.json", `{"port": 8080, "db": {"host": "localhost", "pool": 5}}`},
+			overlays: []file{
+				{"a.yaml", "port: 9090\n"},
+				{"b.toml", "[db]\npool = 10\n"},
+				{"c.jsonc", "// Local override.\n{\"db\": {\"host\": \"db.local\"}}\n"},
+			},
+			expected: testConfig{Port: 9090, DB: dbConfig{Host: "db.local", Pool: 10}},
+		},
+		{
+			name:     "uppercase extensions",
+			base:     file{"config.YAML", "port: 8080\n"},
+			overlays: []file{{"override.Json", `{"port": 9090}`}},
+			expected: testConfig{Port: 9090},
+		},
+		{
+			name:          "malformed json overlay",
+			base:          file{"config.yaml", "port: 8080\n"},
+			overlays:      []file{{"override.json", `{"port": `}},
+			expectedError: "override.json",
+		},
+		{
+			name:          "unsupported overlay format",
+			base:          file{"config.yaml", "port: 8080\n"},
+			overlays:      []file{{"override.txt", "port = 9090\n"}},
+			expectedError: "Unsupported Config Type \"txt\"",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			dir := s.T().TempDir()
+			write := func(f file) string {
+				path := filepath.Join(dir, f.name)
+				s.Require().NoError(os.WriteFile(path, []byte(f.content), 0o600), "write config file")
+				return path
+			}
+			configPath := write(tC.base)
+			overlays := make([]string, 0, len(tC.overlays))
+			for _, overlay := range tC.overlays {
+				overlays = append(overlays, write(overlay))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithAdditionalConfigFiles(overlays...))
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result: got %v, want %v", result, LoadResultContinue)
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvCasing() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+		DB   struct {
+			Host string `mapstructure:"host"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name            string
+		prefix          string
+		caseInsensitive bool
+		env             map[string]string
+		expectedPort    int
+		expectedHost    string
+	}{
+		{
+			name:         "upper case name",
+			prefix:       "TESTAPP",
+			env:          map[string]string{"TESTAPP_PORT": "7070", "TESTAPP_DB_HOST": "db.local"},
+			expectedPort: 7070,
+			expectedHost: "db.local",
+		},
+		{
+			name:         "mixed case prefix is upper cased",
+			prefix:       "TestApp",
+			env:          map[string]string{"TESTAPP_PORT": "7070", "TESTAPP_DB_HOST": "db.local"},
+			expectedPort: 7070,
+			expectedHost: "db.local",
+		},
+		{
+			name:         "other casings ignored by default",
+			prefix:       "TestApp",
+			env:          map[string]string{"TestApp_Port": "7070", "testapp_db_host": "db.local"},
+			expectedPort: 8080,
+		},
+		{
+			name:            "other casings matched",
+			prefix:          "TESTAPP",
+			caseInsensitive: true,
+			env:             map[string]string{"TestApp_Port": "7070", "testapp_db_host": "db.local"},
+			expectedPort:    7070,
+			expectedHost:    "db.local",
+		},
+		{
+			name:            "upper case name wins",
+			prefix:          "TestApp",
+			caseInsensitive: true,
+			env:             map[string]string{"TESTAPP_PORT": "7070", "TestApp_Port": "9090"},
+			expectedPort:    7070,
+		},
+		{
+			name:            "first casing in lexical order wins",
+			prefix:          "TESTAPP",
+			caseInsensitive: true,
+			env:             map[string]string{"testapp_port": "9090", "TestApp_Port": "7070"},
+			expectedPort:    7070,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			var opts []Option
+			if tC.caseInsensitive {
+				opts = append(opts, WithCaseInsensitiveEnv())
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, tC.prefix, opts...)
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expectedPort, cfg.Port, "unexpected port: got %d, want %d", cfg.Port, tC.expectedPort)
+			s.Require().Equal(tC.expectedHost, cfg.DB.Host, "unexpected host: got %q, want %q", cfg.DB.Host, tC.expectedHost)
+		})
+	}
+
+	s.Run("config path, file and tag variables matched", func() {
+		type taggedConfig struct {
+			Port  int    `mapstructure:"port"`
+			Token string `mapstructure:"token"`
+			Mode  string `mapstructure:"mode" env:"APP_MODE"`
+		}
+		tokenPath := s.writeConfigFile("token", []byte("s3cr3t\n"))
+		s.T().Setenv("TestApp_Config", s.writeConfigFile("other.yaml", []byte("port: 6060\n")))
+		s.T().Setenv("TestApp_Token_File", tokenPath)
+		s.T().Setenv("app_mode", "debug")
+
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP", WithCaseInsensitiveEnv())
+		cfg := &taggedConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		expected := taggedConfig{Port: 6060, Token: "s3cr3t", Mode: "debug"}
+		s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+	})
+}
+
+func (s *LoaderSuite) TestLoad_ConfigFormat() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	testCases := []struct {
+		name          string
+		fileName      string
+		content       string
+		opts          []Option
+		args          []string
+		profile       string // Content of the "dev" profile file, if any.
+		flagPath      bool   // The file is given by --config, the default path is missing.
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:          "extensionless file without format",
+			fileName:      "config",
+			content:       "port: 7070\n",
+			expectedError: "set the format with --config-format",
+		},
+		{
+			name:     "extensionless file with flag",
+			fileName: "config",
+			content:  "port: 7070\nhost: localhost\n",
+			args:     []string{"--config-format", "yaml"},
+			expected: testConfig{Port: 7070, Host: "localhost"},
+		},
+		{
+			name:     "extensionless file with option",
+			fileName: "config",
+			content:  `{"port": 7070}`,
+			opts:     []Option{WithConfigFormat("JSON")},
+			expected: testConfig{Port: 7070},
+		},
+		{
+			name:     "flag overrides option",
+			fileName: "config",
+			content:  "port: 7070\n",
+			opts:     []Option{WithConfigFormat("json")},
+			args:     []string{"--config-format", "yaml"},
+			expected: testConfig{Port: 7070},
+		},
+		{
+			name:     "flag overrides extension",
+			fileName: "app.conf",
+			content:  "port = 7070\n",
+			args:     []string{"--config-format", "toml"},
+			expected: testConfig{Port: 7070},
+		},
+		{
+			name:     "profile file decoded in the same format",
+			fileName: "config",
+			content:  "port: 7070\nhost: localhost\n",
+			opts:     []Option{WithProfiles()},
+			args:     []string{"--config-format", "yaml", "--env", "dev"},
+			profile:  "host: dev.local\n",
+			expected: testConfig{Port: 7070, Host: "dev.local"},
+		},
+		{
+			name:          "unsupported format",
+			fileName:      "config",
+			content:       "port: 7070\n",
+			args:          []string{"--config-format", "xml"},
+			expectedError: `unsupported config format "xml"`,
+		},
+		{
+			name:          "unknown extension without format",
+			fileName:      "config.conf",
+			content:       "port: 7070\n",
+			expectedError: `Unsupported Config Type "conf"`,
+		},
+		{
+			name:     "config type forced for default path",
+			fileName: "config.conf",
+			content:  "port: 7070\nhost: localhost\n",
+			opts:     []Option{WithConfigType("yaml")},
+			expected: testConfig{Port: 7070, Host: "localhost"},
+		},
+		{
+			name:     "config type forced for flag path",
+			fileName: "config.conf",
+			content:  "port: 7070\nhost: localhost\n",
+			opts:     []Option{WithConfigType("yaml")},
+			flagPath: true,
+			expected: testConfig{Port: 7070, Host: "localhost"},
+		},
+		{
+			name:     "config type overrides known extension",
+			fileName: "config.json",
+			content:  "port: 7070\n",
+			opts:     []Option{WithConfigType("yaml")},
+			expected: testConfig{Port: 7070},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			dir := s.T().TempDir()
+			configPath := filepath.Join(dir, tC.fileName)
+			s.Require().NoError(os.WriteFile(configPath, []byte(tC.content), 0o600), "write config file")
+			if tC.profile != "" {
+				s.Require().NoError(os.WriteFile(configPath+".dev", []byte(tC.profile), 0o600), "write profile file")
+			}
+
+			loaderPath, args := configPath, tC.args
+			if tC.flagPath {
+				loaderPath = filepath.Join(dir, "missing.conf")
+				args = append(args, "--config", configPath)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", loaderPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_FS() {
+	type testConfig struct {
+		Port     int    `mapstructure:"port"`
+		Host     string `mapstructure:"host"`
+		LogLevel string `mapstructure:"log_level"`
+	}
+
+	fsys := fstest.MapFS{
+		"etc/testapp/config.yaml":     {Data: []byte("port: 8080\nhost: localhost\nlog_level: info\n")},
+		"etc/testapp/config.dev.yaml": {Data: []byte("host: dev.local\n")},
+		"etc/testapp/conf.d/10.json":  {Data: []byte(`{"log_level": "debug"}`)},
+		"overlay.toml":                {Data: []byte("port = 9090\n")},
+		"search/testapp.json":         {Data: []byte(`{"port": 7070}`)},
+	}
+
+	testCases := []struct {
+		name          string
+		configPath    string
+		opts          []Option
+		args          []string
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:       "absolute path",
+			configPath: "/etc/testapp/config.yaml",
+			expected:   testConfig{Port: 8080, Host: "localhost", LogLevel: "info"},
+		},
+		{
+			name:       "profile, config dir and additional file",
+			configPath: "/etc/testapp/config.yaml",
+			opts: []Option{
+				WithProfiles(),
+				WithConfigDir("/etc/testapp/conf.d"),
+				WithAdditionalConfigFiles("overlay.toml"),
+			},
+			args:     []string{"--env", "dev"},
+			expected: testConfig{Port: 9090, Host: "dev.local", LogLevel: "debug"},
+		},
+		{
+			name:     "search by name",
+			opts:     []Option{WithConfigName("testapp", "/search")},
+			expected: testConfig{Port: 7070},
+		},
+		{
+			name:       "cached file",
+			configPath: "etc/testapp/config.yaml",
+			opts:       []Option{WithFileCache()},
+			expected:   testConfig{Port: 8080, Host: "localhost", LogLevel: "info"},
+		},
+		{
+			name:          "missing file",
+			configPath:    "/etc/testapp/missing.yaml",
+			expectedError: "config file not found",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			opts := append([]Option{WithFS(fsys)}, tC.opts...)
+			loader := NewLoader("testapp", "Test App", "", tC.configPath, "TESTAPP", opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadDetailed() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+		DB   struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+	}
+
+	logger := slog.New(slog.DiscardHandler)
+
+	s.Run("config file, env overrides and warnings", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhostname: localhost\n"))
+		s.T().Setenv("TESTAPP_PORT", "7070")
+		s.T().Setenv("TESTAPP_DB_URL", "postgres://override")
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+			WithDeprecatedKeys(map[string]string{"hostname": "host"}), WithLogger(logger))
+		cfg := &testConfig{}
+		report, err := loader.LoadDetailed(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, report.Result, "unexpected load result")
+		s.Require().Equal(configPath, report.ConfigFileUsed, "unexpected config file used")
+		s.Require().Equal([]string{"db.url", "port"}, report.EnvOverrides, "unexpected env overrides")
+		s.Require().Equal([]string{`config key "hostname" is deprecated, use "host" instead`}, report.Warnings,
+			"unexpected warnings")
+		s.Require().Equal(7070, cfg.Port, "unexpected port")
+	})
+
+	s.Run("warnings are not shared between loads", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("hostname: localhost\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+			WithDeprecatedKeys(map[string]string{"hostname": "host"}), WithLogger(logger))
+
+		for range 2 {
+			report, err := loader.LoadDetailed(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Len(report.Warnings, 1, "unexpected warnings: %v", report.Warnings)
+		}
+	})
+
+	s.Run("missing optional config file", func() {
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", WithOptionalConfigFile())
+		report, err := loader.LoadDetailed(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Empty(report.ConfigFileUsed, "no config file must be reported")
+		s.Require().Empty(report.EnvOverrides, "no env overrides must be reported")
+	})
+
+	s.Run("error", func() {
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP")
+		report, err := loader.LoadDetailed(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().Error(err, "expected error, got nil")
+		s.Require().Equal(LoadResultStop, report.Result, "unexpected load result")
+		s.Require().Equal([]ReportError{{Message: err.Error()}}, report.Errors, "unexpected errors")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_ExplicitEnvBindings() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+		DB   struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+		Hosts map[string]string `mapstructure:"hosts"`
+	}
+
+	content := "port: 8080\ndb:\n  url: postgres://localhost\nhosts:\n  api: 10.0.0.1\nunrelated: file\n"
+	env := map[string]string{
+		"TESTAPP_PORT":      "7070",
+		"TESTAPP_UNRELATED": "env",
+		"TESTAPP_DB":        "postgres", // Would shadow the db section.
+		"TESTAPP_HOSTS_API": "10.0.0.2",
+	}
+
+	testCases := []struct {
+		name              string
+		opts              []Option
+		expectedURL       string
+		expectedUnrelated string
+		expectedAPIHost   string
+	}{
+		{
+			name:              "automatic env",
+			expectedUnrelated: "env",
+			expectedAPIHost:   "10.0.0.2",
+		},
+		{
+			name:              "explicit bindings",
+			opts:              []Option{WithExplicitEnvBindings()},
+			expectedURL:       "postgres://localhost",
+			expectedUnrelated: "file",
+			expectedAPIHost:   "10.0.0.1",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			v, _, err := loader.LoadWithViper(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(7070, cfg.Port, "declared key must be overridden by env")
+			s.Require().Equal(tC.expectedURL, cfg.DB.URL, "unexpected db url")
+			s.Require().Equal(tC.expectedAPIHost, cfg.Hosts["api"], "unexpected api host")
+			s.Require().Equal(tC.expectedUnrelated, v.GetString("unrelated"), "unexpected undeclared key")
+		})
+	}
+
+	s.Run("built-in keys read from env", func() {
+		s.T().Setenv("TESTAPP_CONFIG", s.writeConfigFile("other.yaml", []byte("port: 6060\n")))
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", WithExplicitEnvBindings())
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(6060, cfg.Port, "unexpected port")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_ConfigNotFoundHandler() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	s.Run("handler writes default config", func() {
+		configPath := filepath.Join(s.T().TempDir(), "config.yaml")
+		var called []string
+		handler := func(path string) error {
+			called = append(called, path)
+			return os.WriteFile(path, []byte("port: 8080\n"), 0o600)
+		}
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithConfigNotFoundHandler(handler))
+		for range 2 {
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(8080, cfg.Port, "config must be read from the written file")
+		}
+		s.Require().Equal([]string{configPath}, called, "handler must be called once, while the file is missing")
+	})
+
+	s.Run("handler proceeds without file", func() {
+		s.T().Setenv("TESTAPP_PORT", "7070")
+		handler := func(string) error { return nil }
+
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", WithConfigNotFoundHandler(handler))
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(7070, cfg.Port, "config must be read from env")
+	})
+
+	s.Run("handler stops loading", func() {
+		handler := func(string) error { return errSomeError }
+
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", WithConfigNotFoundHandler(handler))
+		result, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().ErrorIs(err, errSomeError, "handler error must be returned")
+		s.Require().ErrorContains(err, `config file not found at "missing.yaml"`, "unexpected error")
+		s.Require().Equal(LoadResultStop, result, "unexpected load result: got %v, want %v", result, LoadResultStop)
+	})
+
+	s.Run("search by name", func() {
+		dir := s.T().TempDir()
+		var called string
+		handler := func(path string) error {
+			called = path
+			return os.WriteFile(path, []byte("port: 9090\n"), 0o600)
+		}
+
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP",
+			WithConfigName("testapp", dir), WithConfigNotFoundHandler(handler))
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(filepath.Join(dir, "testapp.yaml"), called, "unexpected missing path")
+		s.Require().Equal(9090, cfg.Port, "unexpected port")
+	})
+
+	s.Run("malformed file is not handled", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: [\n"))
+		handler := func(string) error {
+			s.Fail("handler must not be called")
+			return nil
+		}
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithConfigNotFoundHandler(handler))
+		_, err := loader.LoadArgs(nil, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().Error(err, "expected error, got nil")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_YAMLAnchors() {
+	type dbConfig struct {
+		Host    string        `mapstructure:"host"`
+		Timeout time.Duration `mapstructure:"timeout"`
+		Retries int           `mapstructure:"retries"`
+		Tags    []string      `mapstructure:"tags"`
+		Extra   bool          `mapstructure:"extra"`
+	}
+	type limits struct {
+		RPS int `mapstructure:"rps"`
+	}
+	type testConfig struct {
+		Primary dbConfig `mapstructure:"primary"`
+		Replica dbConfig `mapstructure:"replica"`
+		Archive dbConfig `mapstructure:"archive"`
+		API     limits   `mapstructure:"api"`
+		Admin   limits   `mapstructure:"admin"`
+	}
+
+	content := `
+defaults: &defaults
+  timeout: 5s
+  retries: 3
+  tags: [a, b]
+primary:
+  <<: *defaults
+  host: primary.local
+replica:
+  <<: *defaults
+  host: replica.local
+  retries: 5
+archive:
+  <<: [*defaults, {extra: true}]
+  host: archive.local
+api: &limits
+  rps: 100
+admin: *limits
+`
+	base := dbConfig{Timeout: 5 * time.Second, Retries: 3, Tags: []string{"a", "b"}}
+	with := func(host string, mutate func(*dbConfig)) dbConfig {
+		db := base
+		db.Host = host
+		if mutate != nil {
+			mutate(&db)
+		}
+		return db
+	}
+
+	testCases := []struct {
+		name     string
+		overlay  string
+		env      map[string]string
+		expected testConfig
+	}{
+		{
+			name: "anchors, aliases and merge keys resolved",
+			expected: testConfig{
+				Primary: with("primary.local", nil),
+				Replica: with("replica.local", func(db *dbConfig) { db.Retries = 5 }),
+				Archive: with("archive.local", func(db *dbConfig) { db.Extra = true }),
+				API:     limits{RPS: 100},
+				Admin:   limits{RPS: 100},
+			},
+		},
+		{
+			name:    "overriding one section leaves the others intact",
+			overlay: `{"admin": {"rps": 10}, "replica": {"timeout": "1s"}}`,
+			env:     map[string]string{"TESTAPP_PRIMARY_RETRIES": "7"},
+			expected: testConfig{
+				Primary: with("primary.local", func(db *dbConfig) { db.Retries = 7 }),
+				Replica: with("replica.local", func(db *dbConfig) { db.Retries, db.Timeout = 5, time.Second }),
+				Archive: with("archive.local", func(db *dbConfig) { db.Extra = true }),
+				API:     limits{RPS: 100},
+				Admin:   limits{RPS: 10},
+			},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			var opts []Option
+			if tC.overlay != "" {
+				opts = append(opts, WithAdditionalConfigFiles(s.writeConfigFile("overlay.json", []byte(tC.overlay))))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestValues_SaveConfig() {
+	type dbConfig struct {
+		URL      string `mapstructure:"url"`
+		Password string `mapstructure:"password" secret:"true"`
+		PoolSize int    `mapstructure:"pool_size" default:"5"`
+	}
+	type testConfig struct {
+		Port    int           `mapstructure:"port"`
+		Timeout time.Duration `mapstructure:"timeout"`
+		Tags    []string      `mapstructure:"tags"`
+		DB      dbConfig      `mapstructure:"db"`
+	}
+
+	content := "port: 8080\ntimeout: 5s\ntags: [a, b]\ndb:\n  url: postgres://localhost\n  password: s3cr3t\n"
+	expected := testConfig{
+		Port:    7070,
+		Timeout: 5 * time.Second,
+		Tags:    []string{"a", "b"},
+		DB:      dbConfig{URL: "postgres://localhost", Password: "s3cr3t", PoolSize: 5},
+	}
+
+	testCases := []struct {
+		name            string
+		fileName        string
+		opts            []Option
+		expectedDefault bool // The default pool_size is written to the file.
+	}{
+		{
+			name:            "yaml",
+			fileName:        "saved.yaml",
+			expectedDefault: true,
+		},
+		{
+			name:            "json",
+			fileName:        "saved.json",
+			expectedDefault: true,
+		},
+		{
+			name:     "defaults omitted",
+			fileName: "saved.yaml",
+			opts:     []Option{WithSaveOmitDefaults()},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			s.T().Setenv("TESTAPP_PORT", "7070")
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			values, _, err := loader.LoadValues(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, expected)
+
+			savedPath := filepath.Join(s.T().TempDir(), tC.fileName)
+			s.Require().NoError(values.SaveConfig(savedPath), "expected nil, got error")
+
+			data, err := os.ReadFile(savedPath)
+			s.Require().NoError(err, "read saved config")
+			s.Require().Contains(string(data), "s3cr3t", "secrets must not be redacted")
+			s.Require().NotContains(string(data), "config", "built-in keys must be omitted")
+			s.Require().Equal(tC.expectedDefault, strings.Contains(string(data), "pool_size"), "unexpected default in:\n%s", data)
+
+			// Round trip: the saved config alone (no env) loads into the same values.
+			s.Require().NoError(os.Unsetenv("TESTAPP_PORT"), "unset env")
+			reloaded := &testConfig{}
+			_, err = NewLoader("testapp", "Test App", "", savedPath, "TESTAPP", tC.opts...).
+				LoadArgs(nil, reloaded, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(*cfg, *reloaded, "unexpected reloaded config: got %+v, want %+v", *reloaded, *cfg)
+		})
+	}
+
+	s.Run("unsupported format", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		values, _, err := loader.LoadValues(&testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		err = values.SaveConfig(filepath.Join(s.T().TempDir(), "saved.xml"))
+		s.Require().ErrorContains(err, "save config to", "unexpected error")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_Sizes() {
+	type testConfig struct {
+		MaxUpload ByteSize  `mapstructure:"max_upload"`
+		Cache     int64     `mapstructure:"cache" size:"true"`
+		Buffer    uint64    `mapstructure:"buffer" size:"true"`
+		Limit     *ByteSize `mapstructure:"limit"`
+		Count     int       `mapstructure:"count"`
+	}
+	size := func(n ByteSize) *ByteSize { return &n }
+
+	testCases := []struct {
+		name          string
+		content       string
+		env           map[string]string
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:    "units from file",
+			content: "max_upload: 10MB\ncache: 1GiB\nbuffer: 512 KiB\nlimit: 1.5kib\ncount: 3\n",
+			expected: testConfig{
+				MaxUpload: 10_000_000,
+				Cache:     1 << 30,
+				Buffer:    512 << 10,
+				Limit:     size(1536),
+				Count:     3,
+			},
+		},
+		{
+			name:     "plain numbers",
+			content:  "max_upload: 1024\ncache: 2048\nbuffer: 100B\n",
+			expected: testConfig{MaxUpload: 1024, Cache: 2048, Buffer: 100},
+		},
+		{
+			name:     "units from env",
+			content:  "max_upload: 10MB\n",
+			env:      map[string]string{"TESTAPP_MAX_UPLOAD": "2KB", "TESTAPP_CACHE": "3MiB", "TESTAPP_BUFFER": "1gb"},
+			expected: testConfig{MaxUpload: 2000, Cache: 3 << 20, Buffer: 1_000_000_000},
+		},
+		{
+			name:          "unknown unit",
+			content:       "max_upload: 10XB\n",
+			expectedError: `invalid size "10XB": unknown unit "XB"`,
+		},
+		{
+			name:          "unknown unit of tagged field",
+			content:       "cache: 10 megabytes\n",
+			expectedError: `'cache' parse size: invalid size "10 megabytes": unknown unit "megabytes"`,
+		},
+		{
+			name:          "missing number",
+			content:       "cache: MB\n",
+			expectedError: `invalid size "MB"`,
+		},
+		{
+			name:          "out of range",
+			content:       "cache: 10000000PiB\n",
+			expectedError: "out of range",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_EnvPrefixFallback() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	testCases := []struct {
+		name             string
+		env              map[string]string
+		expected         testConfig
+		expectedWarnings []string
+	}{
+		{
+			name:     "only old prefix set",
+			env:      map[string]string{"OLD_PORT": "7070"},
+			expected: testConfig{Port: 7070, Host: "localhost"},
+			expectedWarnings: []string{
+				`env variable "OLD_PORT" is deprecated, use "NEW_PORT" instead`,
+			},
+		},
+		{
+			name:     "new prefix wins",
+			env:      map[string]string{"NEW_PORT": "9090", "OLD_PORT": "7070", "LEGACY_PORT": "6060"},
+			expected: testConfig{Port: 9090, Host: "localhost"},
+		},
+		{
+			name:     "first fallback wins",
+			env:      map[string]string{"OLD_PORT": "7070", "LEGACY_PORT": "6060", "LEGACY_HOST": "legacy.local"},
+			expected: testConfig{Port: 7070, Host: "legacy.local"},
+			expectedWarnings: []string{
+				`env variable "LEGACY_HOST" is deprecated, use "NEW_HOST" instead`,
+				`env variable "OLD_PORT" is deprecated, use "NEW_PORT" instead`,
+			},
+		},
+		{
+			name:     "no env",
+			expected: testConfig{Port: 8080, Host: "localhost"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nhost: localhost\n"))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			loader := NewLoader("testapp", "Test App", "", configPath, "NEW",
+				WithEnvPrefixFallback("OLD", "LEGACY"), WithLogger(slog.New(slog.DiscardHandler)))
+			cfg := &testConfig{}
+			report, err := loader.LoadDetailed(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+			s.Require().Equal(tC.expectedWarnings, report.Warnings, "unexpected warnings")
+		})
+	}
+
+	s.Run("config path and env overrides", func() {
+		s.T().Setenv("OLD_CONFIG", s.writeConfigFile("other.yaml", []byte("port: 6060\n")))
+		s.T().Setenv("OLD_HOST", "old.local")
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "NEW",
+			WithEnvPrefixFallback("OLD"), WithLogger(slog.New(slog.DiscardHandler)))
+		cfg := &testConfig{}
+		report, err := loader.LoadDetailed(cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(testConfig{Port: 6060, Host: "old.local"}, *cfg, "unexpected config")
+		s.Require().Equal([]string{"config", "host"}, report.EnvOverrides, "unexpected env overrides")
+	})
+}
+
+func (s *LoaderSuite) TestAttachTo() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name         string
+		args         []string
+		env          map[string]string
+		expectedPort int    // Port, observed by the command run.
+		expectedCmd  string // Name of the command run.
+	}{
+		{
+			name:         "root command",
+			args:         []string{},
+			expectedPort: 8080,
+			expectedCmd:  "app",
+		},
+		{
+			name:         "config flag",
+			args:         []string{"--config", ""}, // Will be set in test.
+			expectedPort: 9090,
+			expectedCmd:  "app",
+		},
+		{
+			name:         "subcommand",
+			args:         []string{"migrate", "-c", ""}, // Will be set in test.
+			expectedPort: 9090,
+			expectedCmd:  "migrate",
+		},
+		{
+			name:         "env override",
+			args:         []string{},
+			env:          map[string]string{"TESTAPP_PORT": "7070"},
+			expectedPort: 7070,
+			expectedCmd:  "app",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			overridePath := filepath.Join(filepath.Dir(configPath), "override.yaml")
+			s.Require().NoError(os.WriteFile(overridePath, []byte("port: 9090\n"), 0o600), "write config file")
+			for i, arg := range tC.args {
+				if arg == "" {
+					tC.args[i] = overridePath
+				}
+			}
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			cfg := &testConfig{}
+			var observedPort int
+			var observedCmd string
+			run := func(c *cobra.Command, _ []string) {
+				observedCmd = c.Name()
+				observedPort = cfg.Port
+			}
+			rootCmd := &cobra.Command{Use: "app", Run: run}
+			rootCmd.AddCommand(&cobra.Command{Use: "migrate", Run: run})
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			s.Require().NoError(loader.AttachTo(rootCmd, cfg), "attach to command")
+			rootCmd.SetArgs(tC.args)
+			s.Require().NoError(rootCmd.Execute(), "execute command")
+
+			s.Require().Equal(tC.expectedCmd, observedCmd, "unexpected command run")
+			s.Require().Equal(tC.expectedPort, observedPort, "unexpected port observed by command")
+		})
+	}
+
+	s.Run("existing pre-run is kept", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		cfg := &testConfig{}
+		var observedPort int
+		rootCmd := &cobra.Command{
+			Use: "app",
+			PersistentPreRunE: func(_ *cobra.Command, _ []string) error {
+				observedPort = cfg.Port
+				return nil
+			},
+			Run: func(_ *cobra.Command, _ []string) {},
+		}
+
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		s.Require().NoError(loader.AttachTo(rootCmd, cfg), "attach to command")
+		rootCmd.SetArgs([]string{})
+		s.Require().NoError(rootCmd.Execute(), "execute command")
+		s.Require().Equal(8080, observedPort, "config must be loaded before the existing pre-run")
+	})
+
+	s.Run("loading error", func() {
+		cfg := &testConfig{}
+		var run bool
+		rootCmd := &cobra.Command{Use: "app", Run: func(_ *cobra.Command, _ []string) { run = true }}
+		rootCmd.SetOut(io.Discard)
+		rootCmd.SetErr(io.Discard)
+
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP")
+		s.Require().NoError(loader.AttachTo(rootCmd, cfg), "attach to command")
+		rootCmd.SetArgs([]string{})
+		s.Require().ErrorIs(rootCmd.Execute(), os.ErrNotExist, "expected missing config error")
+		s.Require().False(run, "command must not run")
+	})
+
+	s.Run("invalid arguments", func() {
+		loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP")
+		s.Require().Error(loader.AttachTo(nil, &testConfig{}), "expected error for nil command")
+		s.Require().Error(loader.AttachTo(&cobra.Command{Use: "app"}, testConfig{}), "expected error for non-pointer cfg")
+
+		rootCmd := &cobra.Command{Use: "app"}
+		rootCmd.Flags().StringP("cert", "c", "", "")
+		err := loader.AttachTo(rootCmd, &testConfig{})
+		s.Require().ErrorContains(err, "collides with the built-in flag --config", "expected collision error")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_RecursiveTypes() {
+	type node struct {
+		Name     string          `mapstructure:"name" default:"node" required:"true"`
+		Next     *node           `mapstructure:"next"`
+		Children map[string]node `mapstructure:"children"`
+	}
+
+	s.Run("nested levels are walked once", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(
+			"next:\n  next:\n    name: deep\nchildren:\n  a:\n    children:\n      b:\n        name: leaf\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &node{}
+		result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+		s.Require().Equal("node", cfg.Name, "default must apply to the top level")
+		s.Require().NotNil(cfg.Next, "nested level must be loaded")
+		s.Require().Empty(cfg.Next.Name, "default must not apply to the recursive level")
+		s.Require().Equal("deep", cfg.Next.Next.Name, "unexpected deep value")
+		s.Require().Empty(cfg.Children["a"].Name, "default must not apply to the recursive map entry")
+		s.Require().Equal("leaf", cfg.Children["a"].Children["b"].Name, "unexpected deep map value")
+	})
+
+	s.Run("cyclic value terminates", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("name: root\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithTrimSpace())
+		cfg := &node{}
+		cfg.Next = cfg
+
+		result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+		s.Require().Equal("root", cfg.Name, "unexpected name")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_AllowEmptyEnv() {
+	type testConfig struct {
+		LogLevel string `mapstructure:"log_level"`
+		Host     string `mapstructure:"host" env:"LEGACY_HOST"`
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		envName  string // Name of the empty log level variable.
+		expected testConfig
+	}{
+		{
+			name:     "empty env ignored by default",
+			envName:  "TESTAPP_LOG_LEVEL",
+			expected: testConfig{LogLevel: "debug", Host: "localhost"},
+		},
+		{
+			name:     "empty env ignored when disabled",
+			opts:     []Option{WithAllowEmptyEnv(false)},
+			envName:  "TESTAPP_LOG_LEVEL",
+			expected: testConfig{LogLevel: "debug", Host: "localhost"},
+		},
+		{
+			name:     "empty env clears file value",
+			opts:     []Option{WithAllowEmptyEnv(true)},
+			envName:  "TESTAPP_LOG_LEVEL",
+			expected: testConfig{},
+		},
+		{
+			name:     "empty env in another casing",
+			opts:     []Option{WithAllowEmptyEnv(true), WithCaseInsensitiveEnv()},
+			envName:  "TestApp_Log_Level",
+			expected: testConfig{},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("log_level: debug\nhost: localhost\n"))
+			s.T().Setenv(tC.envName, "")
+			s.T().Setenv("LEGACY_HOST", "")
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_SetFlag() {
+	type dbConfig struct {
+		Host     string `mapstructure:"host"`
+		PoolSize int    `mapstructure:"pool_size"`
+	}
+	type testConfig struct {
+		DB    dbConfig       `mapstructure:"db"`
+		Code  string         `mapstructure:"code"`
+		Extra map[string]any `mapstructure:"extra"`
+	}
+
+	testCases := []struct {
+		name          string
+		args          []string
+		env           map[string]string
+		expected      testConfig
+		errorContains string
+	}{
+		{
+			name:     "no overrides",
+			args:     []string{},
+			expected: testConfig{DB: dbConfig{Host: "localhost", PoolSize: 10}},
+		},
+		{
+			name:     "nested key over file",
+			args:     []string{"--set", "db.pool_size=20"},
+			expected: testConfig{DB: dbConfig{Host: "localhost", PoolSize: 20}},
+		},
+		{
+			name:     "nested key over env",
+			args:     []string{"--set", "db.pool_size=20"},
+			env:      map[string]string{"TESTAPP_DB_POOL_SIZE": "30"},
+			expected: testConfig{DB: dbConfig{Host: "localhost", PoolSize: 20}},
+		},
+		{
+			name:     "repeated flag, last wins",
+			args:     []string{"--set", "db.host=db.local", "--set", "db.pool_size=20", "--set", "DB.Pool_Size=40"},
+			expected: testConfig{DB: dbConfig{Host: "db.local", PoolSize: 40}},
+		},
+		{
+			name:     "value with equals sign and comma",
+			args:     []string{"--set", "code=a=b,c"},
+			expected: testConfig{DB: dbConfig{Host: "localhost", PoolSize: 10}, Code: "a=b,c"},
+		},
+		{
+			name: "type inference",
+			args: []string{
+				"--set", "code=020", "--set", "extra.flag=true", "--set", "extra.count=3",
+				"--set", "extra.ratio=0.5", "--set", "extra.name=x",
+			},
+			expected: testConfig{
+				DB:    dbConfig{Host: "localhost", PoolSize: 10},
+				Code:  "020",
+				Extra: map[string]any{"flag": true, "count": 3, "ratio": 0.5, "name": "x"},
+			},
+		},
+		{
+			name:          "missing value separator",
+			args:          []string{"--set", "db.pool_size"},
+			errorContains: `invalid --set "db.pool_size": expected key=value`,
+		},
+		{
+			name:          "empty key",
+			args:          []string{"--set", "=20"},
+			errorContains: `invalid --set "=20": expected key=value`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("db:\n  host: localhost\n  pool_size: 10\n"))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithStrictKeys())
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs(tC.args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+
+	s.Run("reported as flag source", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("db:\n  host: localhost\n  pool_size: 10\n"))
+		s.T().Setenv("TESTAPP_DB_POOL_SIZE", "30")
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &testConfig{}
+		v, _, err := loader.loadWithViper([]string{"--set", "db.pool_size=20"}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		sources := loader.KeySources(v)
+		s.Require().Equal(KeySourceFlag, sources["db.pool_size"], "unexpected source of the set key")
+		s.Require().Equal(KeySourceFile, sources["db.host"], "unexpected source of the file key")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_SecureFilePermissions() {
+	if runtime.GOOS == "windows" {
+		s.T().Skip("permission bits are not checked on windows")
+	}
+
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		perm          os.FileMode
+		extraPerm     os.FileMode // Permissions of the additional config file. Zero if none.
+		opts          []Option
+		errorContains string
+	}{
+		{
+			name: "owner only",
+			perm: 0o600,
+			opts: []Option{WithSecureFilePermissions()},
+		},
+		{
+			name: "group readable",
+			perm: 0o640,
+			opts: []Option{WithSecureFilePermissions()},
+		},
+		{
+			name:          "world readable",
+			perm:          0o644,
+			opts:          []Option{WithSecureFilePermissions()},
+			errorContains: "permissions 0644 are too open",
+		},
+		{
+			name:          "world writable",
+			perm:          0o602,
+			opts:          []Option{WithSecureFilePermissions()},
+			errorContains: "permissions 0602 are too open",
+		},
+		{
+			name:          "additional file checked",
+			perm:          0o600,
+			extraPerm:     0o666,
+			opts:          []Option{WithSecureFilePermissions()},
+			errorContains: "permissions 0666 are too open",
+		},
+		{
+			name: "not checked by default",
+			perm: 0o644,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			s.Require().NoError(os.Chmod(configPath, tC.perm), "chmod config file")
+			opts := tC.opts
+			if tC.extraPerm != 0 {
+				extraPath := filepath.Join(filepath.Dir(configPath), "extra.yaml")
+				s.Require().NoError(os.WriteFile(extraPath, []byte("port: 9090\n"), 0o600), "write config file")
+				s.Require().NoError(os.Chmod(extraPath, tC.extraPerm), "chmod config file")
+				opts = append(opts, WithAdditionalConfigFiles(extraPath))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(8080, cfg.Port, "unexpected port")
+		})
+	}
+
+	s.Run("missing optional file", func() {
+		loader := NewLoader("testapp", "Test App", "", filepath.Join(s.T().TempDir(), "missing.yaml"), "TESTAPP",
+			WithSecureFilePermissions(), WithOptionalConfigFile())
+		result, err := loader.LoadArgs([]string{}, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_StrictEnv() {
+	type dbConfig struct {
+		URL string `mapstructure:"url"`
+	}
+	type testConfig struct {
+		Port   int      `mapstructure:"port"`
+		DB     dbConfig `mapstructure:"db"`
+		Legacy string   `mapstructure:"legacy" env:"TESTAPP_OLD_LEGACY"`
+	}
+
+	testCases := []struct {
+		name          string
+		env           map[string]string
+		opts          []Option
+		errorContains string
+	}{
+		{
+			name: "known variables",
+			env: map[string]string{
+				"TESTAPP_PORT":        "9090",
+				"TESTAPP_DB_URL_FILE": "", // Set, but empty: not read.
+				"TESTAPP_CONFIG":      "", // Will be set in test.
+				"TESTAPP_OLD_LEGACY":  "value",
+				"TESTAPP_EXTRA":       "file key",
+				"OTHERAPP_PROT":       "not under the prefix",
+			},
+			opts: []Option{WithStrictEnv()},
+		},
+		{
+			name:          "typo in variable",
+			env:           map[string]string{"TESTAPP_PROT": "9090", "TESTAPP_DB_ULR": "postgres://"},
+			opts:          []Option{WithStrictEnv()},
+			errorContains: "unknown env variables: TESTAPP_DB_ULR, TESTAPP_PROT",
+		},
+		{
+			name:          "whole section",
+			env:           map[string]string{"TESTAPP_DB": "postgres://"},
+			opts:          []Option{WithStrictEnv()},
+			errorContains: "unknown env variables: TESTAPP_DB",
+		},
+		{
+			name:          "another casing",
+			env:           map[string]string{"TestApp_Prot": "9090", "TestApp_Port": "9090"},
+			opts:          []Option{WithStrictEnv(), WithCaseInsensitiveEnv()},
+			errorContains: "unknown env variables: TestApp_Prot",
+		},
+		{
+			name: "env binding",
+			env:  map[string]string{"TESTAPP_FEATURE_FLAG": "true"},
+			opts: []Option{WithStrictEnv(), WithEnvBindings("feature.flag")},
+		},
+		{
+			name: "not checked by default",
+			env:  map[string]string{"TESTAPP_PROT": "9090"},
+		},
+		{
+			name: "not checked without env",
+			env:  map[string]string{"TESTAPP_PROT": "9090"},
+			opts: []Option{WithStrictEnv(), WithoutEnv()},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\nextra: value\n"))
+			for k, v := range tC.env {
+				if k == "TESTAPP_CONFIG" {
+					v = configPath
+				}
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			result, err := loader.LoadArgs([]string{}, &testConfig{}, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+		})
+	}
+}
+
+// testLogLevel is an enum, decoded from its name via encoding.TextUnmarshaler.
+type testLogLevel int
+
+const (
+	testLogLevelInfo testLogLevel = iota
+	testLogLevelDebug
+)
+
+func (l *testLogLevel) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*l = testLogLevelInfo
+	case "debug":
+		*l = testLogLevelDebug
+	default:
+		return fmt.Errorf("unknown log level %q", text)
+	}
+	return nil
+}
+
+func (s *LoaderSuite) TestLoad_TextUnmarshaler() {
+	type testConfig struct {
+		Level    testLogLevel   `mapstructure:"level"`
+		LevelPtr *testLogLevel  `mapstructure:"level_ptr"`
+		Levels   []testLogLevel `mapstructure:"levels"`
+	}
+
+	debug := testLogLevelDebug
+	testCases := []struct {
+		name          string
+		content       string
+		env           map[string]string
+		expected      testConfig
+		errorContains string
+	}{
+		{
+			name:     "from file",
+			content:  "level: debug\nlevel_ptr: debug\nlevels: [info, debug]\n",
+			expected: testConfig{Level: testLogLevelDebug, LevelPtr: &debug, Levels: []testLogLevel{testLogLevelInfo, testLogLevelDebug}},
+		},
+		{
+			name:     "from env",
+			content:  "level: info\n",
+			env:      map[string]string{"TESTAPP_LEVEL": "debug", "TESTAPP_LEVEL_PTR": "debug"},
+			expected: testConfig{Level: testLogLevelDebug, LevelPtr: &debug},
+		},
+		{
+			name:     "numeric value",
+			content:  "level: 1\n",
+			expected: testConfig{Level: testLogLevelDebug},
+		},
+		{
+			name:          "unknown value",
+			content:       "level: trace\n",
+			errorContains: `unknown log level "trace"`,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigURL() {
+	type testConfig struct {
+		Port int    `mapstructure:"port"`
+		Host string `mapstructure:"host"`
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/config.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "port: 8080\nhost: localhost\n")
+	})
+	mux.HandleFunc("/config.dev.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, "host: dev.local\n")
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = io.WriteString(w, `{"port": 9090}`)
+	})
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = io.WriteString(w, "port: 7070\n")
+	})
+	mux.HandleFunc("/broken.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow.yaml", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+		_, _ = io.WriteString(w, "port: 8080\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	tlsServer := httptest.NewTLSServer(mux)
+	defer tlsServer.Close()
+
+	testCases := []struct {
+		name          string
+		url           string
+		args          []string
+		opts          []Option
+		expected      testConfig
+		expectedError string
+	}{
+		{
+			name:     "yaml by extension",
+			url:      server.URL + "/config.yaml",
+			expected: testConfig{Port: 8080, Host: "localhost"},
+		},
+		{
+			name:     "format by content type",
+			url:      server.URL + "/config",
+			expected: testConfig{Port: 9090},
+		},
+		{
+			name:     "format by flag",
+			url:      server.URL + "/plain",
+			args:     []string{"--config-format", "yaml"},
+			expected: testConfig{Port: 7070},
+		},
+		{
+			name:          "unknown format",
+			url:           server.URL + "/plain",
+			expectedError: `can't infer the config format from the URL path or Content-Type "text/plain"`,
+		},
+		{
+			name:     "profile by url",
+			url:      server.URL + "/config.yaml",
+			args:     []string{"--env", "dev"},
+			opts:     []Option{WithProfiles()},
+			expected: testConfig{Port: 8080, Host: "dev.local"},
+		},
+		{
+			name:          "not found",
+			url:           server.URL + "/missing.yaml",
+			expectedError: "config file not found at",
+		},
+		{
+			name: "not found optional",
+			url:  server.URL + "/missing.yaml",
+			opts: []Option{WithOptionalConfigFile()},
+		},
+		{
+			name:          "unexpected status",
+			url:           server.URL + "/broken.yaml",
+			expectedError: "unexpected status 500 Internal Server Error",
+		},
+		{
+			name:          "timeout",
+			url:           server.URL + "/slow.yaml",
+			opts:          []Option{WithHTTPClient(&http.Client{Timeout: 50 * time.Millisecond})},
+			expectedError: "Client.Timeout exceeded",
+		},
+		{
+			name:          "untrusted certificate",
+			url:           tlsServer.URL + "/config.yaml",
+			expectedError: "certificate",
+		},
+		{
+			name:     "trusted certificate",
+			url:      tlsServer.URL + "/config.yaml",
+			opts:     []Option{WithHTTPClient(tlsServer.Client())},
+			expected: testConfig{Port: 8080, Host: "localhost"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			args := append([]string{"--config", tC.url}, tC.args...)
+			v, result, err := loader.loadWithViper(args, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != "" {
+				s.Require().ErrorContains(err, tC.expectedError, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(tC.expected, *cfg, "unexpected config: got %+v, want %+v", *cfg, tC.expected)
+			if tC.expected != (testConfig{}) {
+				s.Require().Equal(tC.url, v.ConfigFileUsed(), "unexpected config file used")
+			}
+		})
+	}
+
+	s.Run("url from env", func() {
+		s.T().Setenv("TESTAPP_CONFIG", server.URL+"/config.yaml")
+		loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP")
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(testConfig{Port: 8080, Host: "localhost"}, *cfg, "unexpected config")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_MaxConfigSize() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	content := "port: 8080\n" // 11 bytes.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		url           bool // The config is fetched from the server instead of the file.
+		opts          []Option
+		extraSize     int // Size of the additional config file. Zero if none.
+		errorContains string
+	}{
+		{
+			name: "under the limit",
+			opts: []Option{WithMaxConfigSize(1024)},
+		},
+		{
+			name: "exactly the limit",
+			opts: []Option{WithMaxConfigSize(int64(len(content)))},
+		},
+		{
+			name:          "over the limit",
+			opts:          []Option{WithMaxConfigSize(10)},
+			errorContains: "is 11 bytes, exceeding the limit of 10 bytes",
+		},
+		{
+			name:          "additional file over the limit",
+			opts:          []Option{WithMaxConfigSize(1024)},
+			extraSize:     2048,
+			errorContains: "is 2048 bytes, exceeding the limit of 1024 bytes",
+		},
+		{
+			name: "url under the limit",
+			url:  true,
+			opts: []Option{WithMaxConfigSize(int64(len(content)))},
+		},
+		{
+			name:          "url over the limit",
+			url:           true,
+			opts:          []Option{WithMaxConfigSize(10)},
+			errorContains: "config exceeds the limit of 10 bytes",
+		},
+		{
+			name:      "no limit by default",
+			extraSize: 2048,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			if tC.url {
+				configPath = server.URL + "/config.yaml"
+			}
+			opts := tC.opts
+			if tC.extraSize > 0 {
+				// A YAML comment of the given size.
+				extraPath := filepath.Join(s.T().TempDir(), "extra.yaml")
+				data := "#" + strings.Repeat("x", tC.extraSize-2) + "\n"
+				s.Require().NoError(os.WriteFile(extraPath, []byte(data), 0o600), "write config file")
+				opts = append(opts, WithAdditionalConfigFiles(extraPath))
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(8080, cfg.Port, "unexpected port")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_JSONEnv() {
+	type replicaConfig struct {
+		URL string `mapstructure:"url"`
+	}
+	type dbConfig struct {
+		URL      string        `mapstructure:"url"`
+		PoolSize int           `mapstructure:"pool_size"`
+		Timeout  time.Duration `mapstructure:"timeout"`
+		Replica  replicaConfig `mapstructure:"replica"`
+	}
+	type testConfig struct {
+		Port   int               `mapstructure:"port"`
+		DB     dbConfig          `mapstructure:"db"`
+		Labels map[string]string `mapstructure:"labels"`
+	}
+
+	testCases := []struct {
+		name          string
+		env           map[string]string
+		opts          []Option
+		expected      dbConfig
+		labels        map[string]string
+		errorContains string
+	}{
+		{
+			name: "whole section",
+			env:  map[string]string{"TESTAPP_DB": `{"url": "postgres://json", "pool_size": 5, "timeout": "3s"}`},
+			expected: dbConfig{
+				URL: "postgres://json", PoolSize: 5, Timeout: 3 * time.Second,
+				Replica: replicaConfig{URL: "postgres://file-replica"},
+			},
+		},
+		{
+			name: "missing keys keep file values",
+			env:  map[string]string{"TESTAPP_DB": `{"pool_size": 5}`},
+			expected: dbConfig{
+				URL: "postgres://file", PoolSize: 5, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://file-replica"},
+			},
+		},
+		{
+			name: "nested object",
+			env:  map[string]string{"TESTAPP_DB": `{"replica": {"url": "postgres://json-replica"}}`},
+			expected: dbConfig{
+				URL: "postgres://file", PoolSize: 10, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://json-replica"},
+			},
+		},
+		{
+			name: "specific variables win",
+			env: map[string]string{
+				"TESTAPP_DB":         `{"url": "postgres://json", "pool_size": 5, "replica": {"url": "postgres://json-replica"}}`,
+				"TESTAPP_DB_URL":     "postgres://env",
+				"TESTAPP_DB_REPLICA": `{"url": "postgres://env-replica"}`,
+			},
+			expected: dbConfig{
+				URL: "postgres://env", PoolSize: 5, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://env-replica"},
+			},
+		},
+		{
+			name: "map field",
+			env:  map[string]string{"TESTAPP_LABELS": `{"team": "core", "tier": "1"}`},
+			expected: dbConfig{
+				URL: "postgres://file", PoolSize: 10, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://file-replica"},
+			},
+			labels: map[string]string{"team": "core", "tier": "1", "env": "file"},
+		},
+		{
+			name: "ignored without env",
+			env:  map[string]string{"TESTAPP_DB": `{"pool_size": 5}`},
+			opts: []Option{WithoutEnv()},
+			expected: dbConfig{
+				URL: "postgres://file", PoolSize: 10, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://file-replica"},
+			},
+		},
+		{
+			name: "known to strict env",
+			env:  map[string]string{"TESTAPP_DB": `{"pool_size": 5}`},
+			opts: []Option{WithStrictEnv()},
+			expected: dbConfig{
+				URL: "postgres://file", PoolSize: 5, Timeout: time.Second,
+				Replica: replicaConfig{URL: "postgres://file-replica"},
+			},
+		},
+		{
+			name:          "invalid JSON",
+			env:           map[string]string{"TESTAPP_DB": `{"pool_size": 5`},
+			errorContains: "'db' decode TESTAPP_DB as JSON",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			content := "port: 8080\n" +
+				"db:\n  url: postgres://file\n  pool_size: 10\n  timeout: 1s\n  replica:\n    url: postgres://file-replica\n" +
+				"labels:\n  env: file\n"
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			cfg := &testConfig{}
+			result, err := loader.LoadArgs([]string{}, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(8080, cfg.Port, "unexpected port")
+			s.Require().Equal(tC.expected, cfg.DB, "unexpected db config")
+			labels := tC.labels
+			if labels == nil {
+				labels = map[string]string{"env": "file"}
+			}
+			s.Require().Equal(labels, cfg.Labels, "unexpected labels")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestConfigKeys() {
+	// The config of examples/simple.
+	type exampleConfig struct {
+		Port     int    `mapstructure:"port"`
+		LogLevel string `mapstructure:"log_level"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+	type upstream struct {
+		URL     string        `mapstructure:"url"`
+		Timeout time.Duration `mapstructure:"timeout"`
+	}
+	type Common struct {
+		Name string `mapstructure:"name"`
+	}
+	type node struct {
+		Value int   `mapstructure:"value"`
+		Next  *node `mapstructure:"next"`
+	}
+	type complexConfig struct {
+		Common    `mapstructure:",squash"`
+		Hosts     []string             `mapstructure:"hosts"`
+		Servers   []upstream           `mapstructure:"servers"`
+		Labels    map[string]string    `mapstructure:"labels"`
+		Upstreams map[string]*upstream `mapstructure:"upstreams"`
+		StartedAt time.Time            `mapstructure:"started_at"`
+		Head      node                 `mapstructure:"head"`
+		Ignored   string               `mapstructure:"-"`
+	}
+
+	testCases := []struct {
+		name     string
+		cfg      any
+		opts     []Option
+		expected []string
+	}{
+		{
+			name:     "example config",
+			cfg:      &exampleConfig{},
+			expected: []string{"port", "log_level", "db.url", "db.pool_size"},
+		},
+		{
+			name: "slices, maps and squashed structs",
+			cfg:  complexConfig{},
+			expected: []string{
+				"name", "hosts", "servers", "labels.<key>", "upstreams.<key>.url", "upstreams.<key>.timeout",
+				"started_at", "head.value", // head.next is of the type being walked.
+			},
+		},
+		{
+			name:     "custom key delimiter",
+			cfg:      &exampleConfig{},
+			opts:     []Option{WithKeyDelimiter("::")},
+			expected: []string{"port", "log_level", "db::url", "db::pool_size"},
+		},
+		{
+			name:     "not a struct",
+			cfg:      map[string]any{"port": 8080},
+			expected: nil,
+		},
+		{
+			name:     "nil",
+			cfg:      nil,
+			expected: nil,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP", tC.opts...)
+			s.Require().Equal(tC.expected, loader.ConfigKeys(tC.cfg), "unexpected keys")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_SetFlagCompletion() {
+	type dbConfig struct {
+		URL      string `mapstructure:"url"`
+		PoolSize int    `mapstructure:"pool_size"`
+	}
+	type testConfig struct {
+		Port   int               `mapstructure:"port"`
+		DB     dbConfig          `mapstructure:"db"`
+		Labels map[string]string `mapstructure:"labels"`
+	}
+
+	testCases := []struct {
+		name       string
+		toComplete string
+		expected   []string
+	}{
+		{
+			name:       "all keys",
+			toComplete: "",
+			expected:   []string{"port=", "db.url=", "db.pool_size=", "labels."},
+		},
+		{
+			name:       "nested keys",
+			toComplete: "db.",
+			expected:   []string{"db.url=", "db.pool_size="},
+		},
+		{
+			name:       "partial key",
+			toComplete: "db.po",
+			expected:   []string{"db.pool_size="},
+		},
+		{
+			name:       "unknown key",
+			toComplete: "prot",
+			expected:   []string{},
+		},
+		{
+			name:       "value",
+			toComplete: "port=",
+			expected:   []string{},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			// The config is not loaded on completion, so it may be missing.
+			loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP")
+			var buf bytes.Buffer
+			args := []string{cobra.ShellCompNoDescRequestCmd, "--set", tC.toComplete}
+			_, err := loader.LoadArgs(args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &buf)
+			s.Require().NoError(err, "expected nil, got error")
+
+			// Suggestions are followed by the directive line (e.g. ":6").
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			s.Require().Equal(tC.expected, lines[:len(lines)-1], "unexpected suggestions")
+			s.Require().True(strings.HasPrefix(lines[len(lines)-1], ":"), "expected the directive line, got %q", lines[len(lines)-1])
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_CompletionCommand() {
+	type testConfig struct {
+		Port int `mapstructure:"port"`
+	}
+
+	testCases := []struct {
+		name          string
+		opts          []Option
+		args          []string
+		expected      LoadResult
+		expectedError bool
+	}{
+		{
+			name:     "bash script",
+			opts:     []Option{WithCompletionCommand()},
+			args:     []string{"completion", "bash"},
+			expected: LoadResultStop,
+		},
+		{
+			name:     "detailed result",
+			opts:     []Option{WithCompletionCommand(), WithDetailedResults()},
+			args:     []string{"completion", "zsh"},
+			expected: LoadResultCommand,
+		},
+		{
+			name:          "disabled by default",
+			args:          []string{"completion", "bash"},
+			expected:      LoadResultStop,
+			expectedError: true,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			// The config is not loaded for the completion script, so it may be missing.
+			loader := NewLoader("testapp", "Test App", "", "missing.yaml", "TESTAPP", tC.opts...)
+			var buf bytes.Buffer
+			result, err := loader.LoadArgs(tC.args, &testConfig{}, PlainVersionPrinter("v1.0.0"), &buf)
+
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+			if tC.expectedError {
+				s.Require().Error(err, "expected error, got nil")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Contains(buf.String(), "testapp", "expected the completion script")
+			s.Require().Contains(buf.String(), cobra.ShellCompRequestCmd, "expected the completion script")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_ConfigCheckOnly() {
+	type testConfig struct {
+		Port     int    `mapstructure:"port" required:"true"`
+		Host     string `mapstructure:"host"`
+		Database struct {
+			URL string `mapstructure:"url" required:"true"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name           string
+		content        string
+		env            map[string]string
+		opts           []Option
+		expectedResult LoadResult
+		expectedJSON   map[string]any
+		errorContains  string
+	}{
+		{
+			name:           "valid config",
+			content:        "port: 8080\nhostname: localhost\ndb:\n  url: postgres://localhost\n",
+			env:            map[string]string{"TESTAPP_PORT": "9090"},
+			expectedResult: LoadResultStop,
+			expectedJSON: map[string]any{
+				"result":           "stop",
+				"config_file_used": "", // Will be set in test.
+				"env_overrides":    []any{"port"},
+				"warnings":         []any{`config key "hostname" is deprecated, use "host" instead`},
+			},
+		},
+		{
+			name:           "detailed result",
+			content:        "port: 8080\ndb:\n  url: postgres://localhost\n",
+			opts:           []Option{WithDetailedResults()},
+			expectedResult: LoadResultCheckConfig,
+			expectedJSON: map[string]any{
+				"result":           "check-config",
+				"config_file_used": "",
+			},
+		},
+		{
+			name:           "invalid config",
+			content:        "host: localhost\n",
+			expectedResult: LoadResultStop,
+			expectedJSON: map[string]any{
+				"result":           "stop",
+				"config_file_used": "",
+				"errors": []any{
+					map[string]any{"key": "port", "message": "'port' is required"},
+					map[string]any{"key": "db.url", "message": "'db.url' is required"},
+				},
+			},
+			errorContains: "'db.url' is required",
+		},
+		{
+			name:           "validator error without key",
+			content:        "port: 8080\ndb:\n  url: postgres://localhost\n",
+			opts:           []Option{WithValidator(func(any) error { return errors.New("port and host mismatch") })},
+			expectedResult: LoadResultStop,
+			expectedJSON: map[string]any{
+				"result":           "stop",
+				"config_file_used": "",
+				"errors":           []any{map[string]any{"message": "port and host mismatch"}},
+			},
+			errorContains: "port and host mismatch",
+		},
+		{
+			name:           "malformed config",
+			content:        "port: [\n",
+			expectedResult: LoadResultStop,
+			expectedJSON: map[string]any{
+				"result":           "stop",
+				"config_file_used": "",
+				"errors":           []any{map[string]any{"message": ""}}, // Will be set in test.
+			},
+			errorContains: "parse config",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(tC.content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			opts := append([]Option{
+				WithConfigCheckOnly(),
+				WithDeprecatedKeys(map[string]string{"hostname": "host"}), // Warnings must be in the report only.
+			}, tC.opts...)
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", opts...)
+			var buf bytes.Buffer
+			result, err := loader.LoadArgs([]string{"--config-check-only"}, &testConfig{}, PlainVersionPrinter("v1.0.0"), &buf)
+
+			var report map[string]any
+			s.Require().NoError(json.Unmarshal(buf.Bytes(), &report), "unmarshal report %q", buf.String())
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				if errs, ok := tC.expectedJSON["errors"].([]any); ok && len(errs) == 1 {
+					if entry := errs[0].(map[string]any); entry["message"] == "" {
+						entry["message"] = strings.TrimPrefix(err.Error(), "execute root command: ")
+					}
+				}
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+				s.Require().Equal(tC.expectedResult, result, "unexpected load result")
+				tC.expectedJSON["config_file_used"] = configPath
+			}
+			s.Require().Equal(tC.expectedJSON, report, "unexpected report")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_Concurrent() {
+	type testConfig struct {
+		Port int `mapstructure:"port" required:"true"`
+		DB   struct {
+			URL string `mapstructure:"url"`
+		} `mapstructure:"db"`
+	}
+
+	const loads = 32
+	paths := make([]string, loads)
+	for i := range loads {
+		paths[i] = s.writeConfigFile("config.yaml", fmt.Appendf(nil, "port: %d\ndb:\n  url: postgres://db-%d\n", 8000+i, i))
+	}
+
+	testCases := []struct {
+		name string
+		opts []Option
+		cmds []*cobra.Command
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "with options",
+			opts: []Option{WithFileCache(), WithEnvHelp(), WithStrictKeys(), WithDetailedResults()},
+		},
+		{
+			name: "with subcommands",
+			cmds: []*cobra.Command{{Use: "migrate", Run: func(*cobra.Command, []string) {}}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			loader := NewLoader("testapp", "Test App", "", "config.yaml", "TESTAPP", tC.opts...)
+			loader.AddCommand(tC.cmds...)
+
+			var wg sync.WaitGroup
+			errs := make(chan error, loads)
+			for i, path := range paths {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					cfg := &testConfig{}
+					var buf bytes.Buffer
+					result, err := loader.LoadArgs([]string{"--config", path}, cfg, PlainVersionPrinter("v1.0.0"), &buf)
+					switch {
+					case err != nil:
+					case result != LoadResultContinue:
+						err = fmt.Errorf("load %d: unexpected result %v", i, result)
+					case cfg.Port != 8000+i || cfg.DB.URL != fmt.Sprintf("postgres://db-%d", i):
+						err = fmt.Errorf("load %d: unexpected config %+v", i, *cfg)
+					}
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoadIntoMap() {
+	content := "port: 8080\nlog_level: info\ndb:\n  url: postgres://localhost\n  pool_size: 5\n"
+
+	testCases := []struct {
+		name           string
+		args           []string
+		env            map[string]string
+		opts           []Option
+		expected       map[string]any
+		expectedResult LoadResult
+		errorContains  string
+	}{
+		{
+			name: "file values",
+			expected: map[string]any{
+				"port": 8080, "log_level": "info",
+				"db": map[string]any{"url": "postgres://localhost", "pool_size": 5},
+			},
+		},
+		{
+			name: "env and flag overrides",
+			args: []string{"--set", "db.pool_size=20"},
+			env:  map[string]string{"TESTAPP_PORT": "9090", "TESTAPP_DB_URL": "postgres://env", "TESTAPP_EXTRA": "ignored"},
+			expected: map[string]any{
+				"port": "9090", "log_level": "info",
+				"db": map[string]any{"url": "postgres://env", "pool_size": 20},
+			},
+		},
+		{
+			name: "env bindings",
+			env:  map[string]string{"TESTAPP_EXTRA_TOKEN": "secret"},
+			opts: []Option{WithEnvBindings("extra.token")},
+			expected: map[string]any{
+				"port": 8080, "log_level": "info",
+				"db":    map[string]any{"url": "postgres://localhost", "pool_size": 5},
+				"extra": map[string]any{"token": "secret"},
+			},
+		},
+		{
+			name: "struct-specific options ignored",
+			opts: []Option{
+				WithStrictKeys(),
+				WithStrictEnv(),
+				WithValidator(func(any) error { return errors.New("must not be called") }),
+			},
+			env: map[string]string{"TESTAPP_PROT": "9090"},
+			expected: map[string]any{
+				"port": 8080, "log_level": "info",
+				"db": map[string]any{"url": "postgres://localhost", "pool_size": 5},
+			},
+		},
+		{
+			name:           "version",
+			args:           []string{"--version"},
+			expectedResult: LoadResultStop,
+		},
+		{
+			name:          "missing config file",
+			args:          []string{"--config", "missing.yaml"},
+			errorContains: "config file not found",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte(content))
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			settings, result, err := loader.loadIntoMap(tC.args, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.errorContains != "" {
+				s.Require().ErrorContains(err, tC.errorContains, "unexpected error")
+				s.Require().Nil(settings, "no map expected on error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(tC.expectedResult, result, "unexpected load result")
+			s.Require().Equal(tC.expected, settings, "unexpected settings")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestMergeReload() {
+	type dbConfig struct {
+		URL      string `mapstructure:"url"`
+		PoolSize int    `mapstructure:"pool_size"`
+	}
+	type testConfig struct {
+		Port   int               `mapstructure:"port"`
+		Host   string            `mapstructure:"host"`
+		DB     *dbConfig         `mapstructure:"db"`
+		Labels map[string]string `mapstructure:"labels"`
+	}
+
+	content := "port: 8080\nhost: localhost\ndb:\n  url: postgres://localhost\n  pool_size: 5\nlabels:\n  team: core\n"
+
+	s.Run("only changed keys are applied", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithMergeReload())
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		db := cfg.DB
+
+		// Runtime modifications.
+		cfg.Host = "runtime.local"
+		cfg.DB.PoolSize = 50
+		cfg.Labels["runtime"] = "true"
+
+		changed := strings.Replace(content, "port: 8080", "port: 9090", 1)
+		s.Require().NoError(os.WriteFile(configPath, []byte(changed), 0o600), "write config file")
+		s.Require().NoError(loader.MergeReload(cfg), "expected nil, got error")
+
+		expected := testConfig{
+			Port:   9090,
+			Host:   "runtime.local",
+			DB:     &dbConfig{URL: "postgres://localhost", PoolSize: 50},
+			Labels: map[string]string{"team": "core", "runtime": "true"},
+		}
+		s.Require().Equal(expected, *cfg, "only the changed port must be applied")
+		s.Require().Same(db, cfg.DB, "nested struct must be updated in place")
+
+		// The base is the previous reload, so the runtime host is still kept, while the pool size is reloaded.
+		changed = strings.Replace(changed, "pool_size: 5", "pool_size: 10", 1)
+		changed = strings.Replace(changed, "team: core", "team: platform", 1)
+		s.Require().NoError(os.WriteFile(configPath, []byte(changed), 0o600), "write config file")
+		s.Require().NoError(loader.MergeReload(cfg), "expected nil, got error")
+
+		expected.DB.PoolSize = 10
+		expected.Labels = map[string]string{"team": "platform"}
+		s.Require().Equal(expected, *cfg, "unexpected config after the second reload")
+	})
+
+	s.Run("removed key", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithMergeReload())
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: 8080\nhost: localhost\n"), 0o600), "write config file")
+		s.Require().NoError(loader.MergeReload(cfg), "expected nil, got error")
+
+		expected := testConfig{Port: 8080, Host: "localhost", DB: &dbConfig{}}
+		s.Require().Equal(expected, *cfg, "removed keys must be zeroed")
+	})
+
+	s.Run("nil nested struct is allocated", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithMergeReload())
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Nil(cfg.DB, "expected nil db")
+
+		s.Require().NoError(os.WriteFile(configPath, []byte("port: 8080\ndb:\n  url: postgres://new\n"), 0o600), "write config file")
+		s.Require().NoError(loader.MergeReload(cfg), "expected nil, got error")
+		s.Require().Equal(&dbConfig{URL: "postgres://new"}, cfg.DB, "unexpected db")
+	})
+
+	s.Run("invalid config leaves cfg intact", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithMergeReload(),
+			WithValidator(func(cfg any) error {
+				if cfg.(*testConfig).Port == 0 {
+					return errors.New("port must be set")
+				}
+				return nil
+			}))
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		s.Require().NoError(os.WriteFile(configPath, []byte("host: db.local\n"), 0o600), "write config file")
+		s.Require().ErrorContains(loader.MergeReload(cfg), "port must be set", "unexpected error")
+		s.Require().Equal("localhost", cfg.Host, "cfg must be intact")
+	})
+
+	s.Run("not loaded", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithMergeReload())
+		s.Require().ErrorContains(loader.MergeReload(&testConfig{}), "was not loaded by the Loader before", "unexpected error")
+		s.Require().Error(loader.MergeReload(testConfig{}), "expected error for a non-pointer config")
+	})
+
+	s.Run("not enabled", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte(content))
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP")
+		cfg := &testConfig{}
+		_, err := loader.LoadArgs(nil, cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+
+		s.Require().Nil(loader.snapshots, "loads must not be retained by default")
+		s.Require().ErrorContains(loader.MergeReload(cfg), "not enabled", "unexpected error")
+	})
+}
+
+func (s *LoaderSuite) TestLoad_VersionFormats() {
+	formats := WithVersionFormats(map[string]func(io.Writer) error{
+		"plain": PlainVersionPrinter("v1.0.0"),
+		"json":  JSONVersionPrinter("v1.0.0", "abc123", "2025-04-05"),
+		"yaml":  YAMLVersionPrinter("v1.0.0", "abc123", "2025-04-05"),
+	})
+
+	testCases := []struct {
+		name        string
+		opts        []Option
+		args        []string
+		expected    string
+		expectedErr string
+	}{
+		{
+			name:     "json",
+			opts:     []Option{formats},
+			args:     []string{"--version", "--version-format", "json"},
+			expected: `{"version":"v1.0.0","commit":"abc123","date":"2025-04-05"}` + "\n",
+		},
+		{
+			name:     "yaml",
+			opts:     []Option{formats},
+			args:     []string{"--version", "--version-format=yaml"},
+			expected: "version: v1.0.0\ncommit: abc123\ndate: \"2025-04-05\"\n",
+		},
+		{
+			name:     "plain",
+			opts:     []Option{formats},
+			args:     []string{"--version", "--version-format", "plain"},
+			expected: "v1.0.0\n",
+		},
+		{
+			name:     "default printer without format",
+			opts:     []Option{formats},
+			args:     []string{"--version"},
+			expected: "default\n",
+		},
+		{
+			name:        "unknown format",
+			opts:        []Option{formats},
+			args:        []string{"--version", "--version-format", "xml"},
+			expectedErr: `unknown version format "xml": expected one of json, plain, yaml`,
+		},
+		{
+			name:        "flag without option",
+			args:        []string{"--version", "--version-format", "json"},
+			expectedErr: "unknown flag: --version-format",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			var buf bytes.Buffer
+			result, err := loader.LoadArgs(tC.args, &struct{}{}, PlainVersionPrinter("default"), &buf)
+
+			if tC.expectedErr != "" {
+				s.Require().ErrorContains(err, tC.expectedErr, "unexpected error")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultStop, result, "unexpected load result")
+			s.Require().Equal(tC.expected, buf.String(), "unexpected version output")
+		})
+	}
+}
+
+func (s *LoaderSuite) TestLoad_KeyTransform() {
+	type dbConfig struct {
+		PoolSize     int `mapstructure:"pool_size"`
+		MaxIdleConns int `mapstructure:"max_idle_conns"`
+	}
+	type config struct {
+		APIURL string            `mapstructure:"api_url"`
+		DB     dbConfig          `mapstructure:"db"`
+		Labels map[string]string `mapstructure:"labels"`
+	}
+
+	testCases := []struct {
+		name     string
+		file     string
+		content  string
+		opts     []Option
+		expected config
+	}{
+		{
+			name:    "camelCase yaml",
+			file:    "config.yaml",
+			content: "apiURL: http://api.local\ndb:\n  poolSize: 10\n  maxIdleConns: 3\nlabels:\n  teamName: core\n",
+			opts:    []Option{WithKeyTransform(SnakeCaseKey)},
+			expected: config{
+				APIURL: "http://api.local",
+				DB:     dbConfig{PoolSize: 10, MaxIdleConns: 3},
+				Labels: map[string]string{"team_name": "core"},
+			},
+		},
+		{
+			name:     "camelCase json",
+			file:     "config.json",
+			content:  `{"db": {"poolSize": 10, "max_idle_conns": 3}}`,
+			opts:     []Option{WithKeyTransform(SnakeCaseKey)},
+			expected: config{DB: dbConfig{PoolSize: 10, MaxIdleConns: 3}},
+		},
+		{
+			name:    "custom transform",
+			file:    "config.yaml",
+			content: "db:\n  pool-size: 10\n  max-idle-conns: 3\n",
+			opts: []Option{WithKeyTransform(func(key string) string {
+				return strings.ReplaceAll(key, "-", "_")
+			})},
+			expected: config{DB: dbConfig{PoolSize: 10, MaxIdleConns: 3}},
+		},
+		{
+			name:     "no transform",
+			file:     "config.yaml",
+			content:  "db:\n  poolSize: 10\n  max_idle_conns: 3\n",
+			expected: config{DB: dbConfig{MaxIdleConns: 3}},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := s.writeConfigFile(tC.file, []byte(tC.content))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", tC.opts...)
+			var cfg config
+			result, err := loader.LoadArgs(nil, &cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+			s.Require().Equal(tC.expected, cfg, "unexpected config")
+		})
+	}
+
+	s.Run("map", func() {
+		loader := NewLoader("testapp", "Test App", "", "", "TESTAPP", WithKeyTransform(SnakeCaseKey))
+		var cfg config
+		_, err := loader.LoadFromMap(&cfg, map[string]any{"db": map[string]any{"poolSize": 10}})
+
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal(10, cfg.DB.PoolSize, "unexpected pool size")
+	})
+}
+
+func (s *LoaderSuite) TestSnakeCaseKey() {
+	testCases := map[string]string{
+		"poolSize":     "pool_size",
+		"PoolSize":     "pool_size",
+		"maxIdleConns": "max_idle_conns",
+		"apiURL":       "api_url",
+		"HTTPServer":   "http_server",
+		"ipv4Addr":     "ipv4_addr",
+		"pool_size":    "pool_size",
+		"port":         "port",
+		"":             "",
+	}
+	for key, expected := range testCases {
+		s.Require().Equal(expected, SnakeCaseKey(key), "unexpected snake case of %q", key)
+	}
+}
+
+// failingSource is a config Source, failing to read.
+type failingSource struct {
+	err error
+}
+
+func (f failingSource) Read() (map[string]any, error) { return nil, f.err }
+
+func (f failingSource) Priority() int { return 0 }
+
+func (s *LoaderSuite) TestLoad_Sources() {
+	type testConfig struct {
+		Port     int    `mapstructure:"port"`
+		Host     string `mapstructure:"host"`
+		LogLevel string `mapstructure:"log_level"`
+		DB       struct {
+			URL      string `mapstructure:"url"`
+			PoolSize int    `mapstructure:"pool_size"`
+		} `mapstructure:"db"`
+	}
+
+	errSource := errors.New("flags service unavailable")
+
+	testCases := []struct {
+		name          string
+		sources       []Source
+		env           map[string]string
+		expected      func(cfg *testConfig)
+		expectedError error
+	}{
+		{
+			name: "negative priority under files",
+			sources: []Source{
+				NewMapSource(map[string]any{"port": 1, "host": "default.local"}, -1),
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Port, cfg.Host = 8080, "default.local"
+			},
+		},
+		{
+			name: "positive priority over files",
+			sources: []Source{
+				NewMapSource(map[string]any{"port": 9090, "db": map[string]any{"pool_size": 20}}, 1),
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Port, cfg.DB.PoolSize = 9090, 20
+			},
+		},
+		{
+			name: "higher priority wins regardless of registration order",
+			sources: []Source{
+				NewMapSource(map[string]any{"port": 9092, "log_level": "debug"}, 2),
+				NewMapSource(map[string]any{"port": 9091, "host": "one.local"}, 1),
+				NewMapSource(map[string]any{"host": "zero.local", "log_level": "warn"}, 0),
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Port, cfg.Host, cfg.LogLevel = 9092, "one.local", "debug"
+			},
+		},
+		{
+			name: "later registered wins on tie",
+			sources: []Source{
+				NewMapSource(map[string]any{"host": "first.local"}, 1),
+				NewMapSource(map[string]any{"host": "second.local"}, 1),
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Port, cfg.Host = 8080, "second.local"
+			},
+		},
+		{
+			name: "nested keys merged",
+			sources: []Source{
+				NewMapSource(map[string]any{"db": map[string]any{"url": "postgres://source"}}, 1),
+			},
+			expected: func(cfg *testConfig) {
+				cfg.Port, cfg.DB.URL, cfg.DB.PoolSize = 8080, "postgres://source", 5
+			},
+		},
+		{
+			name: "env overrides sources",
+			sources: []Source{
+				NewMapSource(map[string]any{"port": 9090}, 10),
+			},
+			env: map[string]string{"TESTAPP_PORT": "7070"},
+			expected: func(cfg *testConfig) {
+				cfg.Port = 7070
+			},
+		},
+		{
+			name:    "nil source ignored",
+			sources: []Source{nil},
+			expected: func(cfg *testConfig) {
+				cfg.Port = 8080
+			},
+		},
+		{
+			name:          "source error",
+			sources:       []Source{failingSource{err: errSource}},
+			expectedError: errSource,
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			for k, v := range tC.env {
+				s.T().Setenv(k, v)
+			}
+			configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\ndb:\n  pool_size: 5\n"))
+			loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithSources(tC.sources...))
+			var cfg testConfig
+			result, err := loader.LoadArgs(nil, &cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if tC.expectedError != nil {
+				s.Require().ErrorIs(err, tC.expectedError, "unexpected error")
+				s.Require().Equal(LoadResultStop, result, "unexpected load result")
+				return
+			}
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Equal(LoadResultContinue, result, "unexpected load result")
+
+			var expected testConfig
+			expected.DB.PoolSize = 5
+			tC.expected(&expected)
+			s.Require().Equal(expected, cfg, "unexpected config")
+		})
+	}
+
+	s.Run("read on each load", func() {
+		configPath := s.writeConfigFile("config.yaml", []byte("port: 8080\n"))
+		values := map[string]any{"host": "first.local"}
+		loader := NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithSources(NewMapSource(values, 1)))
+
+		var cfg testConfig
+		_, err := loader.LoadArgs(nil, &cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("first.local", cfg.Host, "unexpected host")
+
+		values["host"] = "second.local"
+		_, err = loader.LoadArgs(nil, &cfg, PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+		s.Require().NoError(err, "expected nil, got error")
+		s.Require().Equal("second.local", cfg.Host, "unexpected host")
+	})
+}
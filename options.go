@@ -0,0 +1,633 @@
+package configkit
+
+import (
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// Option configures optional Loader behavior.
+type Option func(*Loader)
+
+// WithCheckConfig enables the --check-config flag.
+//
+// When the flag is set, Loader loads and validates the configuration as usual
+// and then stops without running the service. Load returns LoadResultStop on success
+// or an error if the configuration is invalid.
+func WithCheckConfig() Option {
+	return func(l *Loader) {
+		l.checkConfig = true
+	}
+}
+
+// WithEnvKeyReplacer sets the replacer used to map config keys to environment variable names.
+//
+// By default, Loader replaces the key delimiter with "_": db.pool_size → PREFIX_DB_POOL_SIZE.
+// For example, strings.NewReplacer(".", "__") gives PREFIX_DB__POOL_SIZE instead.
+// A nil replacer is ignored.
+func WithEnvKeyReplacer(r *strings.Replacer) Option {
+	return func(l *Loader) {
+		if r != nil {
+			l.envKeyReplacer = r
+		}
+	}
+}
+
+// WithoutEnv disables environment variable support.
+//
+// The config file (and CLI flags) become the single source of truth:
+// neither config values nor the config path (PREFIX_CONFIG) are read from the environment.
+func WithoutEnv() Option {
+	return func(l *Loader) {
+		l.envDisabled = true
+	}
+}
+
+// WithAdditionalConfigFiles adds config files, which are deep-merged on top of the main config file
+// in the given order: later files override earlier ones, nested maps are merged key by key.
+// Each file must exist.
+func WithAdditionalConfigFiles(paths ...string) Option {
+	return func(l *Loader) {
+		for _, path := range paths {
+			l.overlays = append(l.overlays, configOverlay{path: path})
+		}
+	}
+}
+
+// WithOptionalConfigFiles acts like WithAdditionalConfigFiles, but missing files are skipped silently.
+func WithOptionalConfigFiles(paths ...string) Option {
+	return func(l *Loader) {
+		for _, path := range paths {
+			l.overlays = append(l.overlays, configOverlay{path: path, optional: true})
+		}
+	}
+}
+
+// WithEmbeddedDefault sets a default config, baked into the binary (e.g., via go:embed).
+//
+// The embedded config at path within fsys is read first, then the config files and env override it.
+// If the main config file does not exist, Loader falls back to the embedded config instead of failing.
+// format is the embedded config format (e.g., "yaml").
+func WithEmbeddedDefault(fsys fs.FS, path, format string) Option {
+	return func(l *Loader) {
+		if fsys != nil {
+			l.embedded = &embeddedConfig{fsys: fsys, path: path, format: format}
+		}
+	}
+}
+
+// WithKeyDelimiter sets the delimiter, separating nested config keys (default is ".").
+//
+// It is useful when config keys legitimately contain dots (e.g., a map keyed by "example.com").
+// Unless WithEnvKeyReplacer is used, the delimiter is replaced with "_" in env variable names:
+// with "::" delimiter, db::pool_size → PREFIX_DB_POOL_SIZE. An empty delimiter is ignored.
+func WithKeyDelimiter(delim string) Option {
+	return func(l *Loader) {
+		if delim != "" {
+			l.keyDelimiter = delim
+		}
+	}
+}
+
+// WithViperOptions passes opts to viper.NewWithOptions for every viper instance the Loader creates.
+// It is a hook for advanced viper settings, not covered by the Loader options.
+//
+// Note: viper keys are case-insensitive and there is no viper option to change that:
+// keys are lowercased on read, so "apiKey" is stored (and exported) as "apikey".
+// Unmarshaling is not affected, as mapstructure matches field names case-insensitively.
+func WithViperOptions(opts ...viper.Option) Option {
+	return func(l *Loader) {
+		l.viperOpts = append(l.viperOpts, opts...)
+	}
+}
+
+// WithTagName sets the struct tag, which names the config keys of the fields (default: "mapstructure").
+// It allows reusing the existing tags (e.g., `json:"port"` or `yaml:"port"`) instead of adding mapstructure ones.
+// Options after the name (e.g., ",omitempty") are ignored, except for ",squash". An empty name is ignored.
+func WithTagName(name string) Option {
+	return func(l *Loader) {
+		if name != "" {
+			l.tagName = name
+		}
+	}
+}
+
+// WithStrictKeys makes Load fail if the config contains keys which don't map to any struct field
+// (e.g., a typo: "prot" instead of "port"). All unknown keys are reported with their full path.
+func WithStrictKeys() Option {
+	return func(l *Loader) {
+		l.strictKeys = true
+	}
+}
+
+// WithWeaklyTypedInput sets mapstructure's WeaklyTypedInput for decoding the config.
+//
+// Weakly typed input is enabled by default (as in viper): e.g., "8080" string populates an int field.
+// It is required for numeric and boolean fields, overridden via env variables, as env values are always strings.
+// Disabling it makes decoding strict: the value type must match the field type.
+func WithWeaklyTypedInput(enabled bool) Option {
+	return func(l *Loader) {
+		l.weaklyTyped = enabled
+	}
+}
+
+// WithProfiles enables config profiles, selected via the --env/-e flag or PREFIX_ENV variable.
+//
+// The config file of the selected profile is merged on top of the main config file.
+// Its name is derived from the main config path: config.yaml → config.<profile>.yaml.
+// If names are given, only these profiles are allowed. Otherwise, any profile with an existing file is.
+// Selecting an unknown profile is an error.
+func WithProfiles(names ...string) Option {
+	return func(l *Loader) {
+		l.profilesEnabled = true
+		l.profiles = append(l.profiles, names...)
+	}
+}
+
+// WithValidator adds a validation function, which is called with the loaded config after a successful unmarshal
+// (e.g., for cross-field checks or to integrate a validation library). Validators run in the order they were added,
+// after the `required:"true"` fields are checked. All their errors are joined into the single error, returned from Load.
+// They are not called on --help and --version.
+// A nil validator is ignored.
+func WithValidator(validate func(cfg any) error) Option {
+	return func(l *Loader) {
+		if validate != nil {
+			l.validators = append(l.validators, validate)
+		}
+	}
+}
+
+// WithDetailedResults makes Load return the specific stop reason (LoadResultHelp, LoadResultVersion,
+// LoadResultCheckConfig for --check-config and --config-check-only, LoadResultPrintConfig, LoadResultCommand)
+// instead of LoadResultStop, e.g. to set different exit codes.
+// Use LoadResult.IsStop to check whether the program should stop regardless of the reason.
+func WithDetailedResults() Option {
+	return func(l *Loader) {
+		l.detailedResults = true
+	}
+}
+
+// WithRemoteProvider adds a remote key/value store (e.g., etcd, consul) as a config source.
+//
+// The remote config is read via viper's remote support and is merged under the config files:
+// files and env override it. format is the remote config format (e.g., "json").
+// See viper.AddRemoteProvider for the provider, endpoint and path semantics.
+//
+// Remote support must be enabled by the application with a blank import
+// of the viper remote package: _ "github.com/spf13/viper/remote".
+// It is not imported by configkit to keep its dependencies small.
+func WithRemoteProvider(provider, endpoint, path, format string) Option {
+	return func(l *Loader) {
+		l.remote = &remoteProvider{provider: provider, endpoint: endpoint, path: path, format: format}
+	}
+}
+
+// WithRunE sets the service entrypoint, which is run by the root command after a successful config load.
+// It receives the cfg passed to Load. Its error is returned from Load.
+//
+// The entrypoint is not run on --help, --version, --check-config, --config-check-only, --print-config
+// or when a subcommand is executed.
+func WithRunE(run func(cfg any) error) Option {
+	return func(l *Loader) {
+		l.runE = run
+	}
+}
+
+// WithHelpExamples sets the examples section of the --help output.
+func WithHelpExamples(examples string) Option {
+	return func(l *Loader) {
+		l.helpExamples = examples
+	}
+}
+
+// WithEnvHelp appends the "Environment Variables" section to the --help output.
+// It lists the env variables, which override the config keys, derived from the cfg struct mapstructure tags.
+func WithEnvHelp() Option {
+	return func(l *Loader) {
+		l.envHelp = true
+	}
+}
+
+// WithAllowNilWriter makes Load accept a nil writer, replacing it with io.Discard:
+// version and help output is discarded. By default, a nil writer is an error.
+func WithAllowNilWriter() Option {
+	return func(l *Loader) {
+		l.allowNilWriter = true
+	}
+}
+
+// WithConfigName makes Loader search for the config file by its base name (without extension)
+// in the given directories, instead of using the config path passed to NewLoader.
+//
+// Directories are searched in order, and each supported extension is tried in turn
+// (e.g. config.json, config.toml, config.yaml, ...). The first existing file is used.
+// If no directories are given, the current directory is searched.
+// An explicit path via --config (or PREFIX_CONFIG) still takes precedence.
+func WithConfigName(name string, paths ...string) Option {
+	return func(l *Loader) {
+		l.configName = name
+		l.configSearchPaths = paths
+		if len(paths) == 0 {
+			l.configSearchPaths = []string{"."}
+		}
+	}
+}
+
+// WithXDGDefaults makes Loader search for the config file named "config" (e.g., config.yaml)
+// in the XDG-style directories of the app, named after the Loader's name:
+//   - $XDG_CONFIG_HOME/<name> (or $HOME/.config/<name>, if XDG_CONFIG_HOME is not set);
+//   - /etc/<name>;
+//   - the current directory.
+//
+// The search is only performed if no config path is given, either to NewLoader or via --config (or PREFIX_CONFIG).
+// WithConfigName takes precedence over this option.
+func WithXDGDefaults() Option {
+	return func(l *Loader) {
+		l.xdgDefaults = true
+	}
+}
+
+// WithOptionalConfigFile makes the main config file optional: if it doesn't exist
+// (either at the default path or at an explicit --config path), Loader proceeds with env
+// and other config sources only, instead of failing. Malformed files are still an error.
+//
+// Not to be confused with WithOptionalConfigFiles, which adds optional overlay files.
+func WithOptionalConfigFile() Option {
+	return func(l *Loader) {
+		l.configOptional = true
+	}
+}
+
+// WithEnvBindings explicitly binds config keys (e.g., "db.password") to env variables.
+//
+// Env variables are only applied to the keys, known to viper: the ones present in config sources
+// and the ones of the cfg struct. Bound keys are read from env (e.g., PREFIX_DB_PASSWORD) as well,
+// which is handy for the keys outside of cfg, looked up via LoadWithViper (e.g., map entries).
+// Has no effect with WithoutEnv.
+func WithEnvBindings(keys ...string) Option {
+	return func(l *Loader) {
+		l.envBindings = append(l.envBindings, keys...)
+	}
+}
+
+// WithPrintConfig enables the --print-config flag.
+//
+// When the flag is set, Loader loads the configuration as usual, prints the merged config in YAML format
+// to the writer and then stops without running the service. Values of the fields,
+// tagged with `secret:"true"`, are redacted. Load returns LoadResultStop on success.
+func WithPrintConfig() Option {
+	return func(l *Loader) {
+		l.printConfig = true
+	}
+}
+
+// WithPreRun adds a hook, run right before the config loading (e.g., to set up logging).
+//
+// Hooks run in the order they were added, for the root command and the subcommands alike,
+// but not on --help and --version. An error of the hook aborts the loading.
+func WithPreRun(hook func() error) Option {
+	return func(l *Loader) {
+		if hook != nil {
+			l.preRun = append(l.preRun, hook)
+		}
+	}
+}
+
+// WithPostRun adds a hook, run right after a successful config loading and validation.
+//
+// Hooks run in the order they were added, before the WithRunE entrypoint or the subcommand.
+// Like WithPreRun hooks, they don't run on --help and --version. An error of the hook is returned from Load.
+func WithPostRun(hook func() error) Option {
+	return func(l *Loader) {
+		if hook != nil {
+			l.postRun = append(l.postRun, hook)
+		}
+	}
+}
+
+// WithLogger sets the logger for the loading diagnostics: config files read, env overrides, fallbacks, etc.
+// Messages are logged at the debug level. The keys, overridden by env or flags, are logged with their values,
+// except for the fields tagged with `secret:"true"`, which values are masked.
+// By default, the Loader is silent.
+func WithLogger(logger *slog.Logger) Option {
+	return func(l *Loader) {
+		l.logger = logger
+	}
+}
+
+// WithTrimSpace makes Loader trim the leading and trailing whitespace from all string config values
+// (including string pointers, slices, map values and the fields of map entries) after unmarshal. It is useful for the values, read from env
+// or mounted secret files, which often carry a trailing newline.
+//
+// Fields, tagged with `trim:"false"`, keep their whitespace.
+func WithTrimSpace() Option {
+	return func(l *Loader) {
+		l.trimSpace = true
+	}
+}
+
+// WithEnvPrefixFlag enables the --env-prefix flag, which overrides the env prefix, passed to NewLoader,
+// at runtime (e.g., --env-prefix OTHER makes OTHER_PORT set the port instead of PREFIX_PORT).
+// It is handy for running the same binary under different identities.
+//
+// The override applies to all env variables, including PREFIX_CONFIG and the _FILE ones.
+// Env variables, bound with the env tag, are not affected. Has no effect with WithoutEnv.
+func WithEnvPrefixFlag() Option {
+	return func(l *Loader) {
+		l.envPrefixFlag = true
+	}
+}
+
+// WithValidateCommand enables the `config validate [path]` subcommand, which validates the config file
+// without running the service (e.g., in CI): the file at path (or the configured one, if omitted) is read along with
+// all other config sources, decoded and validated the same way Load does.
+//
+// On success, "OK" is printed to the writer and Load returns LoadResultStop (LoadResultCommand with
+// WithDetailedResults). Otherwise, Load returns the error, so the process may exit with a non-zero code.
+func WithValidateCommand() Option {
+	return func(l *Loader) {
+		l.validateCommand = true
+	}
+}
+
+// WithConfigDir makes Loader merge all config files of dir (conf.d style) on top of the main config
+// (and the profile one, if any) in lexical order of their names, e.g. 00-base.yaml, then 10-override.yaml.
+//
+// Files of all supported formats are merged, others (and subdirectories) are skipped. An empty directory is fine,
+// a missing one is an error. Files, added by WithAdditionalConfigFiles, are merged on top of the directories.
+// Multiple directories are merged in the order they were added.
+func WithConfigDir(dir string) Option {
+	return func(l *Loader) {
+		l.configDirs = append(l.configDirs, dir)
+	}
+}
+
+// WithEnvExpansion makes Loader expand ${VAR} references in the string config values with the values of the env
+// variables before unmarshal (e.g., `url: "postgres://${DB_HOST}:5432"`). Values of the env variables and files,
+// referenced by PREFIX_KEY_FILE, are not expanded.
+//
+// Only the braced form is expanded, other dollar signs are left as is (e.g., "$$LITERAL" or "pa$word").
+// "$${VAR}" escapes the reference, resulting in the literal "${VAR}".
+// Referencing an unset env variable is an error, a variable, set to an empty string, expands to it.
+func WithEnvExpansion() Option {
+	return func(l *Loader) {
+		l.envExpansion = true
+	}
+}
+
+// WithDeprecatedKeys keeps the renamed config keys working: keys maps the deprecated keys to their replacements
+// (e.g., "db.host_name" → "db.host"). If a deprecated key is set (in a file, env, etc.), its value is used
+// for the new key, and a deprecation warning is reported. If both keys are set, the new one wins,
+// and the deprecated one is ignored with a warning.
+//
+// Warnings are logged at the warning level with the logger (see WithLogger), or written to the writer, passed to Load,
+// otherwise.
+// Deprecated keys are not reported as unknown by WithStrictKeys. Multiple calls add up.
+func WithDeprecatedKeys(keys map[string]string) Option {
+	return func(l *Loader) {
+		if l.deprecatedKeys == nil {
+			l.deprecatedKeys = make(map[string]string, len(keys))
+		}
+		for old, key := range keys {
+			l.deprecatedKeys[strings.ToLower(old)] = strings.ToLower(key)
+		}
+	}
+}
+
+// WithFileCache enables caching of the parsed config files across the loads of the Loader (e.g., a server,
+// reloading the config on request). A file is parsed again only if its modification time or size has changed,
+// otherwise the cached result is merged. The cache is safe for concurrent use.
+//
+// Note that a file, rewritten within the modification time resolution of the file system with the same size,
+// is not detected as changed.
+func WithFileCache() Option {
+	return func(l *Loader) {
+		l.fileCache = newFileCache()
+	}
+}
+
+// WithCaseInsensitiveEnv matches the env variable names case-insensitively.
+//
+// By default, env variable names are derived in upper case, whatever the casing of the prefix and keys is
+// (e.g., prefix "TestApp" and key db.host → TESTAPP_DB_HOST), and only the exact names are looked up.
+// So, on case-sensitive platforms TestApp_DB_Host is ignored. With the option, it is used instead:
+// the upper case name still wins if set, and of the other casings the first one in lexical order is used.
+//
+// The option applies to all env variables, read by the Loader (including PREFIX_CONFIG, the _FILE ones
+// and the names of the env tags), except for the ${VAR} references of WithEnvExpansion.
+func WithCaseInsensitiveEnv() Option {
+	return func(l *Loader) {
+		l.caseInsensitiveEnv = true
+	}
+}
+
+// WithConfigFormat sets the format of the main config file (e.g., "yaml"), instead of inferring it
+// from the file extension. It suits extensionless files (e.g., /etc/myapp/config).
+// The format applies to the profile config file and PREFIX_CONFIG_B64 as well.
+//
+// The format is the default of the --config-format flag, which takes precedence.
+func WithConfigFormat(format string) Option {
+	return func(l *Loader) {
+		l.configFormat = strings.ToLower(format)
+	}
+}
+
+// WithFS makes the Loader read the config files from fsys instead of the OS file system
+// (e.g., fstest.MapFS in tests, or an embed.FS with a set of bundled configs).
+//
+// It applies to all config files: the main one (including the search by name), the profile, config dirs
+// and additional files. Paths are resolved within fsys with the leading slash trimmed:
+// /etc/myapp/config.yaml is read as etc/myapp/config.yaml. The files, referenced by the _FILE
+// env variables, are still read from the OS file system, as they are provided by the environment.
+func WithFS(fsys fs.FS) Option {
+	return func(l *Loader) {
+		l.fsys = fsys
+	}
+}
+
+// WithExplicitEnvBindings makes env variables apply only to the keys, declared by the config struct
+// (and the ones of WithEnvBindings), instead of any key under the prefix.
+//
+// By default, viper's AutomaticEnv matches every key it knows: e.g. the keys, present in a config file
+// but not in the struct, and the entries of map fields. An unrelated variable may also collide with a section:
+// TESTAPP_DB=postgres shadows all the db.* keys. With the option, such variables are ignored.
+// The built-in keys (e.g., PREFIX_CONFIG) are still read from env.
+func WithExplicitEnvBindings() Option {
+	return func(l *Loader) {
+		l.explicitEnv = true
+	}
+}
+
+// WithConfigNotFoundHandler sets the handler, deciding what to do when the main config file is missing
+// (e.g., write a default config, or proceed without it).
+//
+// The handler is called with the path of the missing file. If the file is searched by name (WithConfigName),
+// it is the path of the YAML file with that name in the first search directory. If the handler returns an error,
+// the loading fails with it. Otherwise, the file is read again (so the handler may create it), and if it is
+// still missing, the config is loaded from the other sources, as WithOptionalConfigFile does.
+func WithConfigNotFoundHandler(handler func(path string) error) Option {
+	return func(l *Loader) {
+		l.configNotFound = handler
+	}
+}
+
+// WithSaveOmitDefaults makes Values.SaveConfig write only the keys, explicitly set by a config file
+// or an env variable (see Values.FieldWasSet), omitting the ones holding default values only.
+func WithSaveOmitDefaults() Option {
+	return func(l *Loader) {
+		l.saveOmitDefaults = true
+	}
+}
+
+// WithEnvPrefixFallback sets the env prefixes, checked in order for the keys, not set with the main prefix
+// (e.g., when transitioning from OLD_ to NEW_: NEW_PORT is checked first, then OLD_PORT).
+//
+// The first variable found wins. Using a fallback variable is reported as deprecated with a warning
+// (to the WithLogger logger, or the writer of Load otherwise). The fallbacks apply to the built-in keys (e.g., OLD_CONFIG)
+// as well, but not to the _FILE variables. Multiple calls add up.
+func WithEnvPrefixFallback(prefixes ...string) Option {
+	return func(l *Loader) {
+		l.fallbackPrefixes = append(l.fallbackPrefixes, prefixes...)
+	}
+}
+
+// WithAllowEmptyEnv sets whether env variables, set to an empty string, count as set (viper's AllowEmptyEnv).
+//
+// By default, empty variables are ignored, as if they were not set. With the option enabled,
+// an empty variable (e.g., PREFIX_LOG_LEVEL=) overrides the value of the config file, clearing the field.
+// It applies to the `env:"NAME"` tags and fallback prefixes as well, but not to the _FILE and _B64 variables.
+func WithAllowEmptyEnv(enabled bool) Option {
+	return func(l *Loader) {
+		l.allowEmptyEnv = enabled
+	}
+}
+
+// WithSecureFilePermissions refuses to load the config files, accessible by others: if any of the "others"
+// permission bits is set (e.g., 0644 or 0666), the loading fails. Owner and group access is fine (e.g., 0600 or 0640).
+//
+// It protects the configs with secrets from being left world-readable or world-writable. All config files are checked:
+// main, profile, config dirs and additional ones. The check is skipped on Windows, as the permission bits
+// don't reflect the actual access rights there.
+func WithSecureFilePermissions() Option {
+	return func(l *Loader) {
+		l.securePerms = true
+	}
+}
+
+// WithStrictEnv makes Load fail if an env variable under the prefix doesn't correspond to any config key
+// (e.g., PREFIX_PROT instead of PREFIX_PORT), the way WithStrictKeys does for the config files.
+//
+// The known keys are the ones of the config struct, the config files and WithEnvBindings, as well as
+// the built-in ones (e.g., PREFIX_CONFIG). The _FILE variables of the known keys and the names of
+// the `env:"NAME"` tags are known too. Without a prefix, env variables are not checked.
+func WithStrictEnv() Option {
+	return func(l *Loader) {
+		l.strictEnv = true
+	}
+}
+
+// WithConfigType is an alias of WithConfigFormat, named after viper's SetConfigType: it forces the format
+// of the main config file (e.g., "yaml" for config.conf), regardless of its extension. The format applies
+// to the default config path and the one, given by --config, alike.
+func WithConfigType(configType string) Option {
+	return WithConfigFormat(configType)
+}
+
+// WithHTTPClient sets the client to fetch the configs by http(s) URL with (e.g., --config https://config.local/app.yaml),
+// to set up the timeout, TLS (e.g., a private CA) or authentication.
+//
+// By default, a client with a 10 seconds timeout is used.
+func WithHTTPClient(client *http.Client) Option {
+	return func(l *Loader) {
+		l.client = client
+	}
+}
+
+// WithMaxConfigSize limits the size of the config files to maxBytes, so a huge or wrong file (e.g., a log),
+// given by mistake, fails the loading instead of being read. The size is checked before reading the file.
+// The limit applies to all config files, as well as to the configs fetched by URL.
+//
+// By default, the size is not limited. Zero or negative maxBytes removes the limit.
+func WithMaxConfigSize(maxBytes int64) Option {
+	return func(l *Loader) {
+		l.maxConfigSize = maxBytes
+	}
+}
+
+// WithConfigCheckOnly enables the --config-check-only flag, the machine-readable counterpart of --check-config
+// for CI pipelines.
+//
+// When the flag is set, Loader loads and validates the configuration as usual, then writes the LoadReport
+// as JSON to the writer and stops without running the service: the result, the config file used,
+// the keys overridden by env, the warnings and the errors (with the config keys of the invalid fields, if known).
+// Load returns LoadResultStop on success or an error if the configuration is invalid, so the exit code
+// tells the outcome as well.
+func WithConfigCheckOnly() Option {
+	return func(l *Loader) {
+		l.checkOnly = true
+	}
+}
+
+// WithVersionFormats enables the --version-format flag, selecting the version printer at runtime
+// among printers by their names (e.g., "plain", "json" and "yaml"): --version --version-format json.
+//
+// Without --version-format, the printVersion function, passed to Load, is used. An unknown format
+// is an error, listing the available ones. The flag has no effect without --version.
+func WithVersionFormats(printers map[string]func(io.Writer) error) Option {
+	return func(l *Loader) {
+		l.versionFormats = printers
+	}
+}
+
+// WithKeyTransform applies transform to the keys of the config sources on decoding, before viper lowercases them,
+// so the keys, which don't match the struct tags, still map to the fields. E.g., WithKeyTransform(SnakeCaseKey)
+// maps the camelCase poolSize key to the pool_size field, which viper alone would read as poolsize.
+//
+// The transform applies to each key of the nested maps, including the entry names of map fields, in all config
+// sources: files, configs fetched by URL, PREFIX_CONFIG_B64, the embedded and remote configs, custom sources (see Source),
+// LoadFromReader and LoadFromMap.
+// Env variables and --set are not affected, as they are named after the config keys already.
+// Note that viper.WithDecoderRegistry, passed via WithViperOptions, takes precedence and disables the transform.
+func WithKeyTransform(transform func(key string) string) Option {
+	return func(l *Loader) {
+		l.keyTransform = transform
+	}
+}
+
+// WithSources registers custom config sources (e.g., a database or a feature flag service), read on each load
+// and merged with the config files by priority (see Source). May be used multiple times, nil sources are ignored.
+//
+// An error of a source fails the loading. Note that the main config file is still required,
+// unless WithOptionalConfigFile is used.
+func WithSources(sources ...Source) Option {
+	return func(l *Loader) {
+		l.sources = append(l.sources, sources...)
+	}
+}
+
+// WithMergeReload enables MergeReload: on each successful load (by Load and the like, AttachTo, WatchSignal
+// or MergeReload) the raw values of the config keys are stored as the base of the next MergeReload of that cfg.
+//
+// The Loader keeps the loaded configs, so they are not garbage collected while the Loader is in use.
+// Load the same cfg again rather than a new one each time (e.g., with LoadInto), or the memory grows with each load.
+// By default, nothing is kept.
+func WithMergeReload() Option {
+	return func(l *Loader) {
+		l.snapshots = &sync.Map{}
+	}
+}
+
+// WithCompletionCommand enables the `completion [bash|zsh|fish|powershell]` subcommand of cobra, which prints
+// the shell completion script (e.g., for the --set keys) to the writer. The config is not loaded for it.
+//
+// Load returns LoadResultStop (LoadResultCommand with WithDetailedResults) after the script is printed.
+func WithCompletionCommand() Option {
+	return func(l *Loader) {
+		l.completionCommand = true
+	}
+}
@@ -0,0 +1,425 @@
+package configkit
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// readConfig reads all config sources into v, in the order of increasing priority:
+// embedded default, remote config, main config file at configPath (or the base64-encoded config
+// from PREFIX_CONFIG_B64 instead, unless the path is given by --config), profile config file,
+// config directories, additional config files.
+//
+// If configPath is empty, the main config file is searched by name (see WithConfigName and WithXDGDefaults).
+// The path of the main config file is set as the config file of v, if the file was read.
+func (l *Loader) readConfig(v *viper.Viper, configPath string) error {
+	format, err := l.mainConfigFormat(v)
+	if err != nil {
+		return err
+	}
+
+	if err := l.mergeEmbedded(v); err != nil {
+		return err
+	}
+	if err := l.readRemote(v); err != nil {
+		return err
+	}
+	if err := l.mergeSources(v, false); err != nil {
+		return err
+	}
+
+	var used string
+	name := l.envVarName(v, "config") + configB64Suffix
+	if !l.envDisabled && l.getenv(name) != "" && !l.configPathExplicit(v) {
+		// The encoded config replaces the main config file. An explicit path wins, as flags take precedence over env.
+		if err := l.mergeEncodedConfig(v, name, configPath, format); err != nil {
+			return err
+		}
+	} else {
+		resolved, read, err := l.mergeMainFile(v, configPath, format)
+		if err != nil {
+			return err
+		}
+		configPath = resolved
+		if read {
+			used = resolved
+		}
+	}
+
+	if err := l.mergeProfile(v, configPath, format); err != nil {
+		return err
+	}
+	if err := l.mergeConfigDirs(v); err != nil {
+		return err
+	}
+	if err := l.mergeOverlays(v); err != nil {
+		return err
+	}
+	if err := l.mergeSources(v, true); err != nil {
+		return err
+	}
+	v.SetConfigFile(used)
+
+	return nil
+}
+
+// configPathExplicit reports whether the main config file path is given explicitly
+// (by --config or `config validate <path>`), rather than by env or not at all.
+func (l *Loader) configPathExplicit(v *viper.Viper) bool {
+	path, _ := l.valuesWithoutEnv(v, []string{"config"})["config"].(string)
+	return path != ""
+}
+
+// mergeMainFile merges the main config file at configPath (or the one, found by name) into v.
+// The file is decoded as format, unless it is empty. It returns the resolved path of the file
+// and whether the file was read (a missing file may be skipped).
+//
+// If the file is missing, the WithConfigNotFoundHandler handler is called, and the file is read again:
+// the handler may have created it. Otherwise, the missing file is skipped, if there is something to fall back to.
+func (l *Loader) mergeMainFile(v *viper.Viper, configPath, format string) (string, bool, error) {
+	resolved, err := l.resolveConfigPath(configPath)
+	if err == nil {
+		err = l.mergeMainFileAt(v, resolved, format)
+	}
+
+	if errors.Is(err, fs.ErrNotExist) && l.configNotFound != nil {
+		resolved = l.missingConfigPath(resolved)
+		if err := l.configNotFound(resolved); err != nil {
+			return "", false, fmt.Errorf("config file not found at %q: %w", resolved, err)
+		}
+		if err = l.mergeMainFileAt(v, resolved, format); errors.Is(err, fs.ErrNotExist) {
+			l.log().Debug("config file not found, skipping", "path", redactConfigPath(resolved))
+			return resolved, false, nil
+		}
+	}
+
+	// Missing main config file is not an error, if there is something to fall back to.
+	missingAllowed := l.embedded != nil || l.configOptional
+	switch {
+	case err == nil:
+		l.log().Debug("read config file", "path", redactConfigPath(resolved))
+		return resolved, true, nil
+	case missingAllowed && errors.Is(err, fs.ErrNotExist):
+		// Falling back to the embedded default or env only.
+		l.log().Debug("config file not found, skipping", "path", redactConfigPath(resolved))
+		return resolved, false, nil
+	default:
+		return "", false, err
+	}
+}
+
+// mergeMainFileAt merges the main config file at path into v, decoding it as format (unless empty).
+// A missing file (or an empty path) results in fs.ErrNotExist.
+func (l *Loader) mergeMainFileAt(v *viper.Viper, path, format string) error {
+	if path == "" {
+		return fmt.Errorf("config file path not set: %w", fs.ErrNotExist)
+	}
+
+	// Merging (rather than reading) keeps the embedded default values, not present in the file.
+	err := l.mergeFileAs(v, path, format)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("config file not found at %q: %w", redactConfigPath(path), err)
+	case err != nil:
+		return fmt.Errorf("read main config at %q: %w", redactConfigPath(path), err)
+	}
+	return nil
+}
+
+// missingConfigPath returns the path of the missing main config file, reported to the WithConfigNotFoundHandler handler.
+// If the file is searched by name, it is the YAML file of that name in the first search directory.
+func (l *Loader) missingConfigPath(path string) string {
+	name, dirs := l.configSearch()
+	if path != "" || name == "" || len(dirs) == 0 {
+		return path
+	}
+	return filepath.Join(dirs[0], name+".yaml")
+}
+
+// mergeEncodedConfig merges the base64-encoded config from the env variable name into v.
+// The format is inferred from the extension of configPath, unless set explicitly.
+// YAML is assumed otherwise (JSON is decoded as well).
+func (l *Loader) mergeEncodedConfig(v *viper.Viper, name, configPath, format string) error {
+	// Line breaks (e.g., of the base64 output, wrapped by default) are ignored.
+	encoded := strings.Join(strings.Fields(l.getenv(name)), "")
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return fmt.Errorf("decode %s: %w", name, err)
+	}
+
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(configPath), "."))
+	}
+	if !isSupportedFormat(format) {
+		format = "yaml"
+	}
+	if err := l.mergeData(v, data, format); err != nil {
+		return fmt.Errorf("read config from %s: %w", name, asParseError(name, err))
+	}
+	l.log().Debug("read config from env", "env", name, "format", format)
+
+	return nil
+}
+
+// resolveConfigPath returns configPath as is, if set. Otherwise, it searches for the config file
+// named after WithConfigName in its search paths, trying each supported extension in turn.
+func (l *Loader) resolveConfigPath(configPath string) (string, error) {
+	name, dirs := l.configSearch()
+	if configPath != "" || name == "" {
+		return configPath, nil
+	}
+
+	exts := append(slices.Clone(viper.SupportedExts), formatJSONC)
+	for _, dir := range dirs {
+		for _, ext := range exts {
+			path := filepath.Join(dir, name+"."+ext)
+			if info, err := l.stat(path); err == nil && !info.IsDir() {
+				l.log().Debug("found config file", "name", name, "path", path)
+				return path, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("config file %q not found in %s: %w", name, strings.Join(dirs, ", "), fs.ErrNotExist)
+}
+
+// configSearch returns the base name of the config file to search for and the directories to search it in.
+// The name is empty if the config file is not searched for.
+func (l *Loader) configSearch() (name string, dirs []string) {
+	switch {
+	case l.configName != "":
+		return l.configName, l.configSearchPaths
+	case l.xdgDefaults:
+		return "config", l.xdgSearchPaths()
+	default:
+		return "", nil
+	}
+}
+
+// xdgSearchPaths returns the XDG-style config directories of the app: $XDG_CONFIG_HOME/<name>
+// (or $HOME/.config/<name>), /etc/<name> and the current directory.
+func (l *Loader) xdgSearchPaths() []string {
+	var dirs []string
+
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			base = filepath.Join(home, ".config")
+		}
+	}
+	if base != "" {
+		dirs = append(dirs, filepath.Join(base, l.name))
+	}
+
+	return append(dirs, filepath.Join("/etc", l.name), ".")
+}
+
+// mergeEmbedded merges the embedded default config into v, if any.
+func (l *Loader) mergeEmbedded(v *viper.Viper) error {
+	if l.embedded == nil {
+		return nil
+	}
+
+	data, err := fs.ReadFile(l.embedded.fsys, l.embedded.path)
+	if err != nil {
+		return fmt.Errorf("read embedded config at %q: %w", l.embedded.path, err)
+	}
+
+	if err := l.mergeData(v, data, l.embedded.format); err != nil {
+		return fmt.Errorf("merge embedded config at %q: %w", l.embedded.path, asParseError(l.embedded.path, err))
+	}
+	l.log().Debug("merged embedded config", "path", l.embedded.path)
+
+	return nil
+}
+
+// readRemote merges the remote config into v, if any.
+func (l *Loader) readRemote(v *viper.Viper) error {
+	if l.remote == nil {
+		return nil
+	}
+
+	// Reading in a separate instance, so the type of the files is still inferred from their extensions.
+	tmp := viper.NewWithOptions(l.viperOptions()...)
+	if err := tmp.AddRemoteProvider(l.remote.provider, l.remote.endpoint, l.remote.path); err != nil {
+		return fmt.Errorf("add remote provider %q: %w", l.remote.provider, err)
+	}
+	tmp.SetConfigType(l.remote.format)
+	if err := tmp.ReadRemoteConfig(); err != nil {
+		return fmt.Errorf("read remote config from %s at %q (path %q): %w",
+			l.remote.provider, l.remote.endpoint, l.remote.path, err)
+	}
+	if err := v.MergeConfigMap(tmp.AllSettings()); err != nil {
+		return fmt.Errorf("merge remote config from %s: %w", l.remote.provider, err)
+	}
+	l.log().Debug("merged remote config", "provider", l.remote.provider, "endpoint", l.remote.endpoint, "path", l.remote.path)
+
+	return nil
+}
+
+// mergeProfile merges the config file of the selected profile into v, if any.
+// Profile file name is derived from configPath: config.yaml → config.<profile>.yaml.
+// The file is decoded as format (the one of the main config file), unless it is empty.
+func (l *Loader) mergeProfile(v *viper.Viper, configPath, format string) error {
+	if !l.profilesEnabled {
+		return nil
+	}
+	profile := v.GetString("env")
+	if profile == "" {
+		return nil
+	}
+	if len(l.profiles) > 0 && !slices.Contains(l.profiles, profile) {
+		return fmt.Errorf("unknown profile %q: expected one of %s", profile, strings.Join(l.profiles, ", "))
+	}
+
+	ext := filepath.Ext(configPath)
+	profilePath := strings.TrimSuffix(configPath, ext) + "." + profile + ext
+	if err := l.mergeFileAs(v, profilePath, format); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unknown profile %q: config file not found at %q", profile, redactConfigPath(profilePath))
+		}
+		return fmt.Errorf("merge profile config at %q: %w", redactConfigPath(profilePath), err)
+	}
+	l.log().Debug("merged profile config", "profile", profile, "path", redactConfigPath(profilePath))
+
+	return nil
+}
+
+// mergeOverlays deep-merges additional config files into v in the order they were added.
+// Missing optional files are skipped.
+func (l *Loader) mergeOverlays(v *viper.Viper) error {
+	for _, overlay := range l.overlays {
+		if err := l.mergeFile(v, overlay.path); err != nil {
+			if overlay.optional && errors.Is(err, fs.ErrNotExist) {
+				l.log().Debug("optional config file not found, skipping", "path", overlay.path)
+				continue
+			}
+			return fmt.Errorf("merge config at %q: %w", overlay.path, err)
+		}
+		l.log().Debug("merged config file", "path", overlay.path)
+	}
+	return nil
+}
+
+// mergeConfigDirs merges the config files of the directories, set by WithConfigDir, into v.
+// Files of each directory are merged in lexical order. Subdirectories and files of unsupported formats are skipped.
+func (l *Loader) mergeConfigDirs(v *viper.Viper) error {
+	exts := append(slices.Clone(viper.SupportedExts), formatJSONC)
+	for _, dir := range l.configDirs {
+		entries, err := l.readDir(dir) // Sorted by name.
+		if err != nil {
+			return fmt.Errorf("read config dir: %w", err)
+		}
+
+		for _, entry := range entries {
+			ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(entry.Name()), "."))
+			if entry.IsDir() || !slices.Contains(exts, ext) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if err := l.mergeFile(v, path); err != nil {
+				return fmt.Errorf("merge config at %q: %w", path, err)
+			}
+			l.log().Debug("merged config file", "path", path)
+		}
+	}
+	return nil
+}
+
+// mergeFile merges the config file at path into v. The config type is inferred from the file extension
+// of each file separately (case-insensitive), so files of different formats can be merged together.
+// Malformed files result in ConfigParseError.
+func (l *Loader) mergeFile(v *viper.Viper, path string) error {
+	return l.mergeFileAs(v, path, "")
+}
+
+// mergeFileAs merges the config file at path into v, decoding it as format.
+// If format is empty, it is inferred from the file extension, the way mergeFile does.
+// If path is an http(s) URL, the config is fetched instead (see mergeURL).
+func (l *Loader) mergeFileAs(v *viper.Viper, path, format string) error {
+	if isConfigURL(path) {
+		return l.mergeURL(v, path, format)
+	}
+	if format == "" {
+		var err error
+		if format, err = fileFormat(path); err != nil {
+			return err
+		}
+	}
+	if err := l.checkFile(path); err != nil {
+		return err
+	}
+	if l.fileCache != nil {
+		return l.mergeCachedFile(v, path, format)
+	}
+
+	data, err := l.readFile(path)
+	if err != nil {
+		return err
+	}
+	return asParseError(path, l.mergeData(v, data, format))
+}
+
+// fileFormat returns the config type of the file at path, inferred from its extension (lowercased).
+func fileFormat(path string) (string, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if format == "" {
+		return "", fmt.Errorf("config file %q has no extension to infer the format from"+
+			" - set the format with --config-format (or WithConfigFormat)", path)
+	}
+	if !isSupportedFormat(format) {
+		return "", viper.UnsupportedConfigError(format)
+	}
+	return format, nil
+}
+
+// isSupportedFormat reports whether the config format (lowercased) can be decoded.
+func isSupportedFormat(format string) bool {
+	return slices.Contains(viper.SupportedExts, format) || format == formatJSONC
+}
+
+// mainConfigFormat returns the format of the main config file, set by --config-format or WithConfigFormat.
+// Empty means the format is inferred from the file extension.
+func (l *Loader) mainConfigFormat(v *viper.Viper) (string, error) {
+	format := strings.ToLower(cmp.Or(v.GetString("config-format"), l.configFormat))
+	if format != "" && !isSupportedFormat(format) {
+		return "", fmt.Errorf("unsupported config format %q", format)
+	}
+	return format, nil
+}
+
+// mergeData decodes data of the given format and merges it into v.
+//
+// Decoding is performed in a separate instance, as viper's config type is sticky:
+// once set, it can't be reset, and the type of the files wouldn't be inferred from their extensions anymore.
+func (l *Loader) mergeData(v *viper.Viper, data []byte, format string) error {
+	settings, err := l.decodeData(data, format)
+	if err != nil {
+		return err
+	}
+	return v.MergeConfigMap(settings)
+}
+
+// decodeData decodes data of the given format into the nested settings map.
+func (l *Loader) decodeData(data []byte, format string) (map[string]any, error) {
+	if format == formatJSONC {
+		data, format = stripJSONComments(data), "json"
+	}
+
+	tmp := viper.NewWithOptions(l.viperOptions()...)
+	tmp.SetConfigType(format)
+	if err := tmp.ReadConfig(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return tmp.AllSettings(), nil
+}
@@ -0,0 +1,107 @@
+package configkit
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// secretTag marks config fields, which values must not be exposed (e.g., `secret:"true"`).
+const secretTag = "secret"
+
+// redactedValue replaces the values of the secret fields.
+const redactedValue = "******"
+
+// secretKeys returns the config keys of the cfg fields, tagged with `secret:"true"`, lowercased the way viper
+// reports them (e.g., apikey for the apiKey tag).
+//
+// Fields of map[string]Struct values are walked per map entry, present in v (e.g., upstreams.<name>.password),
+// the same way the defaults are set.
+func (l *Loader) secretKeys(v *viper.Viper, cfg any) []string {
+	var keys []string
+	l.collectSecretKeys(v, reflect.TypeOf(cfg), "", map[reflect.Type]bool{}, &keys)
+	return keys
+}
+
+// collectSecretKeys appends the secret keys of the struct type t fields, prefixed with prefix, to keys.
+// visited holds the struct types on the current path to break cycles.
+func (l *Loader) collectSecretKeys(
+	v *viper.Viper,
+	t reflect.Type,
+	prefix string,
+	visited map[reflect.Type]bool,
+	keys *[]string,
+) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if visited[t] {
+		return
+	}
+	visited[t] = true
+	defer delete(visited, t)
+
+	walkStructKeys(t, prefix, l.keyFormat(), func(key string, field reflect.StructField) {
+		if field.Tag.Get(secretTag) == "true" {
+			*keys = append(*keys, strings.ToLower(key))
+			return
+		}
+
+		elem, ok := structMapElem(field.Type)
+		if !ok {
+			return
+		}
+		names := slices.Sorted(maps.Keys(v.GetStringMap(key)))
+		for _, name := range names {
+			l.collectSecretKeys(v, elem, key+l.keyDelimiter+name+l.keyDelimiter, visited, keys)
+		}
+	})
+}
+
+// isSecretKey reports whether the config key is one of the secret keys, or is nested in one of them
+// (e.g., tokens.api for the secret map field tokens). Keys are compared case-insensitively, as viper lowercases them.
+func isSecretKey(key string, secrets []string, delim string) bool {
+	key = strings.ToLower(key)
+	return slices.ContainsFunc(secrets, func(secret string) bool {
+		secret = strings.ToLower(secret)
+		return key == secret || strings.HasPrefix(key, secret+delim)
+	})
+}
+
+// redactedSettings returns the merged settings of v without the built-in keys.
+// Values of the secret fields of cfg are replaced with a placeholder.
+func (l *Loader) redactedSettings(v *viper.Viper, cfg any) map[string]any {
+	settings := v.AllSettings()
+	for _, key := range l.builtinKeys() {
+		delete(settings, key)
+	}
+
+	for _, key := range l.secretKeys(v, cfg) {
+		path := strings.Split(key, l.keyDelimiter)
+		m := settings
+		for _, name := range path[:len(path)-1] {
+			m, _ = m[name].(map[string]any) // Nil if not set, lookups below are safe.
+		}
+		if name := path[len(path)-1]; m[name] != nil {
+			m[name] = redactedValue
+		}
+	}
+
+	return settings
+}
+
+// printEffectiveConfig writes the redacted merged config of v to w in YAML format.
+func (l *Loader) printEffectiveConfig(w io.Writer, v *viper.Viper, cfg any) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(l.redactedSettings(v, cfg)); err != nil {
+		return fmt.Errorf("encode config: %w", err)
+	}
+	return enc.Close()
+}
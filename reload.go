@@ -0,0 +1,216 @@
+package configkit
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Reload loads the config into a new value of T and returns it, leaving the previously loaded values intact.
+// It suits immutable config patterns: each call returns an independent value, so no mutable state is shared.
+//
+// Unlike Load, the command line is not parsed again: the built-in flags (e.g., --config, --env or --set),
+// parsed by the last successful load of the Loader (Load, LoadArgs, AttachTo and the like), are reused,
+// so the same config files are read. Before any load, the config path is taken from PREFIX_CONFIG
+// or the Loader settings (NewLoader, WithConfigName). Config files, env variables and validation are processed as usual.
+//
+// Returns:
+//   - the new config and LoadResultContinue: if config was loaded successfully.
+//   - nil, LoadResultStop and error: if there was a problem (e.g. config file not found).
+func Reload[T any](l *Loader) (*T, LoadResult, error) {
+	cfg := new(T)
+	v, err := l.newReloadViper()
+	if err != nil {
+		return nil, LoadResultStop, fmt.Errorf("reload config: %w", err)
+	}
+
+	if err := l.loadConfig(v, cfg); err != nil {
+		return nil, LoadResultStop, fmt.Errorf("reload config: %w", err)
+	}
+	if err := l.validate(cfg); err != nil {
+		return nil, LoadResultStop, fmt.Errorf("reload config: %w", err)
+	}
+
+	return cfg, LoadResultContinue, nil
+}
+
+// MergeReload reads the config again the way WatchSignal does (including the built-in flags of the last load)
+// and applies only the changed keys to cfg, leaving the rest of it intact. It suits hot reloads,
+// keeping the fields, modified at runtime, unless their config values change.
+//
+// A key is changed, if its value in the config sources differs from the one of the previous load of cfg
+// (by Load and the like, AttachTo, WatchSignal or MergeReload), e.g. it was edited, added or removed
+// in the config file. The fields of the changed keys are set to the newly loaded values. Maps and slices
+// are replaced as a whole.
+//
+// MergeReload must be enabled with WithMergeReload. cfg must be a pointer to a struct, previously loaded
+// by the same Loader. The new config is validated as a whole before any field is set, so on error cfg is left intact.
+func (l *Loader) MergeReload(cfg any) error {
+	if err := validateTarget(cfg); err != nil {
+		return err
+	}
+	if l.snapshots == nil {
+		return fmt.Errorf("merge reload: not enabled (see WithMergeReload)")
+	}
+	base, ok := l.snapshots.Load(cfg)
+	if !ok {
+		return fmt.Errorf("merge reload: cfg was not loaded by the Loader before")
+	}
+
+	fresh, v, err := l.loadFresh(cfg)
+	if err != nil {
+		return err
+	}
+
+	snapshot := l.snapshot(v, cfg)
+	changed := make(map[string]bool)
+	for key, value := range snapshot {
+		if !reflect.DeepEqual(value, base.(map[string]any)[key]) {
+			changed[key] = true
+		}
+	}
+	mergeChanged(reflect.ValueOf(cfg).Elem(), fresh.Elem(), "", l.keyFormat(), changed, map[reflect.Type]bool{})
+	l.snapshots.Store(cfg, snapshot)
+
+	for _, key := range slices.Sorted(maps.Keys(changed)) {
+		l.log().Debug("config key reloaded", "key", key)
+	}
+	return nil
+}
+
+// rememberLoad stores the raw values of the config keys of cfg, it was loaded with by v, as the base of MergeReload.
+// It is a no-op unless WithMergeReload is used.
+func (l *Loader) rememberLoad(v *viper.Viper, cfg any) {
+	if l.snapshots != nil {
+		l.snapshots.Store(cfg, l.snapshot(v, cfg))
+	}
+}
+
+// snapshot returns the raw values of the config keys of cfg (see walkStructKeys) in v. Unset keys are nil.
+func (l *Loader) snapshot(v *viper.Viper, cfg any) map[string]any {
+	values := make(map[string]any)
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, _ reflect.StructField) {
+		values[key] = v.Get(key)
+	})
+	return values
+}
+
+// mergeChanged sets the fields of the struct value dst, which keys (prefixed with prefix) are changed,
+// to the ones of src of the same type. Keys are built the same way walkStructKeys does.
+// Nil pointers to nested structs of dst are allocated, if any of their keys is changed.
+// visited holds the struct types on the current path to break cycles.
+func mergeChanged(
+	dst, src reflect.Value,
+	prefix string,
+	kf keyFormat,
+	changed map[string]bool,
+	visited map[reflect.Type]bool,
+) {
+	if visited[dst.Type()] {
+		return
+	}
+	visited[dst.Type()] = true
+	defer delete(visited, dst.Type())
+
+	for _, sf := range structFields(dst.Type(), kf.tag) {
+		df, sField := dst.Field(sf.index), src.Field(sf.index)
+		switch {
+		case sf.squash && sf.elem.Kind() == reflect.Struct, sf.kind == fieldStruct:
+			nested := prefix
+			if !sf.squash {
+				nested = prefix + sf.name + kf.delim
+			}
+			if !hasChangedKey(changed, nested) {
+				continue
+			}
+			for df.Kind() == reflect.Ptr {
+				if df.IsNil() {
+					df.Set(reflect.New(df.Type().Elem()))
+				}
+				df = df.Elem()
+			}
+			for sField.Kind() == reflect.Ptr {
+				if sField.IsNil() {
+					sField = reflect.Zero(sField.Type().Elem())
+					continue
+				}
+				sField = sField.Elem()
+			}
+			mergeChanged(df, sField, nested, kf, changed, visited)
+		case changed[prefix+sf.name]:
+			df.Set(sField)
+		}
+	}
+}
+
+// hasChangedKey reports whether any of the changed keys starts with prefix.
+func hasChangedKey(changed map[string]bool, prefix string) bool {
+	for key := range changed {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadedFlags holds the values of the built-in flags, changed on the last successful load, to be reused by the reloads.
+type loadedFlags struct {
+	mu     sync.Mutex
+	values map[string][]string // Flag name → its values (a single one for the non-repeatable flags).
+}
+
+// remember stores the values of the changed built-in flags of fs. Other flags (e.g., the ones of the command,
+// the Loader is attached to) are ignored on reload.
+func (f *loadedFlags) remember(fs *pflag.FlagSet) {
+	values := make(map[string][]string)
+	// Visiting all, as the flags are parsed by the flag set of the executed command, sharing them.
+	fs.VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			values[flag.Name] = sv.GetSlice()
+			return
+		}
+		values[flag.Name] = []string{flag.Value.String()}
+	})
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values = values
+}
+
+// newReloadViper returns a new viper instance with the built-in flags bound, set to the values of the last load.
+func (l *Loader) newReloadViper() (*viper.Viper, error) {
+	l.flags.mu.Lock()
+	loaded := l.flags.values // Replaced as a whole on each load, never modified.
+	l.flags.mu.Unlock()
+
+	fs := l.persistentFlags()
+	for name, values := range loaded {
+		flag := fs.Lookup(name)
+		if flag == nil {
+			continue
+		}
+		if sv, ok := flag.Value.(pflag.SliceValue); ok {
+			_ = sv.Replace(values) // Never fails for string slices.
+			flag.Changed = true
+			continue
+		}
+		if err := fs.Set(name, values[0]); err != nil {
+			return nil, fmt.Errorf("restore flag --%s: %w", name, err)
+		}
+	}
+
+	v := l.newViper()
+	if err := l.bindPersistentFlags(v, fs); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
@@ -0,0 +1,145 @@
+package configkit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// checkOnlyFlag is the name of the flag, enabled by WithConfigCheckOnly.
+const checkOnlyFlag = "config-check-only"
+
+// LoadReport is the detailed outcome of LoadDetailed. It is also written as JSON on --config-check-only.
+type LoadReport struct {
+	// Result of the load, the same Load returns.
+	Result LoadResult `json:"result"`
+	// Path of the main config file read. Empty if none (e.g., a missing optional file).
+	ConfigFileUsed string `json:"config_file_used"`
+	// Config keys, overridden by env variables, sorted.
+	EnvOverrides []string `json:"env_overrides,omitempty"`
+	// Warnings, reported during the load (e.g., deprecated keys in use).
+	Warnings []string `json:"warnings,omitempty"`
+	// Errors, the load failed with. Empty on success.
+	Errors []ReportError `json:"errors,omitempty"`
+}
+
+// ReportError is an error of the load in the LoadReport.
+type ReportError struct {
+	Key     string `json:"key,omitempty"` // Config key of the invalid field (see FieldError). Empty if unknown.
+	Message string `json:"message"`       // Error message.
+}
+
+// LoadDetailed acts like Load, but returns the report on the load along with its result:
+// the config file used, the keys overridden by env variables and the warnings.
+//
+// The report is returned on error as well, with the warnings reported before the failure and the errors.
+// On --help and --version the config is not read, so no config file is reported.
+func (l *Loader) LoadDetailed(cfg any, printVersion func(io.Writer) error, writer io.Writer) (LoadReport, error) {
+	var args []string
+	if len(os.Args) > 1 {
+		args = os.Args[1:]
+	}
+	return l.loadDetailed(args, cfg, printVersion, writer)
+}
+
+// loadDetailed implements LoadDetailed, parsing args (without the program name).
+func (l *Loader) loadDetailed(
+	args []string,
+	cfg any,
+	printVersion func(io.Writer) error,
+	writer io.Writer,
+) (LoadReport, error) {
+	// Collecting the warnings in a copy, so the Loader itself stays stateless.
+	var warnings []string
+	lc := *l
+	lc.warnings = &warnings
+
+	v, result, err := lc.loadWithViper(args, cfg, printVersion, writer)
+	report := LoadReport{Result: result, Warnings: warnings, Errors: reportErrors(err)}
+	if err != nil {
+		return report, err
+	}
+	l.describeLoad(&report, v)
+
+	return report, nil
+}
+
+// describeLoad fills the config file used and the env overrides of the report from v, the config was loaded with.
+func (l *Loader) describeLoad(report *LoadReport, v *viper.Viper) {
+	report.ConfigFileUsed = v.ConfigFileUsed()
+	for key, source := range l.KeySources(v) {
+		if source == KeySourceEnv {
+			report.EnvOverrides = append(report.EnvOverrides, key)
+		}
+	}
+	slices.Sort(report.EnvOverrides)
+}
+
+// checkConfigOnly loads and validates the config into cfg the way Load does, then writes the LoadReport
+// as JSON to writer (see WithConfigCheckOnly). The loading or validation error is returned after the report is written.
+func (l *Loader) checkConfigOnly(v *viper.Viper, cfg any, writer io.Writer) error {
+	// Collecting the warnings in a copy, so the Loader itself stays stateless.
+	// They are not written to writer, as the report must be the only output there.
+	var warnings []string
+	lc := *l
+	lc.warnings, lc.warnWriter = &warnings, nil
+
+	err := lc.loadConfig(v, cfg)
+	if err == nil {
+		err = lc.validate(cfg)
+	}
+
+	report := LoadReport{Result: LoadResultStop, Warnings: warnings, Errors: reportErrors(err)}
+	if l.detailedResults {
+		report.Result = LoadResultCheckConfig
+	}
+	if err == nil {
+		l.describeLoad(&report, v)
+	}
+
+	if encodeErr := json.NewEncoder(writer).Encode(report); encodeErr != nil {
+		return fmt.Errorf("write config check report: %w", encodeErr)
+	}
+	return err
+}
+
+// reportErrors returns the report entries of err: one per error, joined by errors.Join
+// (e.g., each failed field of the validation), or a single one otherwise. Returns nil if err is nil.
+func reportErrors(err error) []ReportError {
+	if err == nil {
+		return nil
+	}
+	errs := splitErrors(err)
+	entries := make([]ReportError, 0, len(errs))
+	for _, e := range errs {
+		entry := ReportError{Message: e.Error()}
+		var fieldErr *FieldError
+		if errors.As(e, &fieldErr) {
+			entry.Key = fieldErr.Key
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// splitErrors returns the errors, joined by errors.Join into err (possibly wrapped, e.g., "validate config: ..."),
+// with the nested joins flattened. If no errors are joined, err itself is returned.
+func splitErrors(err error) []error {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		joined, ok := e.(interface{ Unwrap() []error })
+		if !ok {
+			continue
+		}
+		var errs []error
+		for _, je := range joined.Unwrap() {
+			errs = append(errs, splitErrors(je)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
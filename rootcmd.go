@@ -1,19 +1,37 @@
 package configkit
 
 import (
+	"cmp"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"reflect"
+	"slices"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
+// builtinKeys returns the viper keys of the built-in flags, enabled for the Loader.
+func (l *Loader) builtinKeys() []string {
+	keys := []string{"config", "config-format", setKey, "version"}
+	if l.profilesEnabled {
+		keys = append(keys, "env")
+	}
+	if l.envPrefixFlag {
+		keys = append(keys, "env-prefix")
+	}
+	return keys
+}
+
 // buildRootCommand builds root command.
 //
-// Method declares flags and binds them to actions. It also enables env variables.
-// If any of the env variables is set, it will overrride the config file.
+// Method declares flags and binds them to actions. Env variables are expected to be set up on v
+// by the caller. If any of the env variables is set, it will overrride the config file.
 //
 // The result of the execution - is a built up command, ready to execute.
 // All reading/setting is perfromed on the pre-run stage.
@@ -24,58 +42,117 @@ func (l *Loader) buildRootCommand(
 	writer io.Writer,
 ) (*cobra.Command, error) {
 	rootCmd := &cobra.Command{
-		Use:   l.name,
-		Short: l.short,
-		Long:  l.long,
-		Run: func(_ *cobra.Command, _ []string) {
-			// Service logic is expected to be handled elsewhere.
+		Use:     l.name,
+		Short:   l.short,
+		Long:    l.helpLong(v, cfg),
+		Example: l.helpExamples,
+		RunE: func(c *cobra.Command, _ []string) error {
+			// Service logic is expected to be handled elsewhere, unless the entrypoint is set.
+			stopped := v.GetBool("version") || c.Flags().Changed("check-config") || c.Flags().Changed(checkOnlyFlag) ||
+				c.Flags().Changed("print-config")
+			if l.runE == nil || stopped {
+				return nil
+			}
+			if err := l.runE(cfg); err != nil {
+				return fmt.Errorf("run: %w", err)
+			}
+			return nil
 		},
+		// Completion command is not needed for a service, unless enabled explicitly.
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: !l.completionCommand},
+	}
+	rootCmd.SetOut(writer)
+	rootCmd.AddCommand(l.commands...)
+	if l.validateCommand {
+		rootCmd.AddCommand(l.newConfigCommand(v, cfg, writer))
 	}
 
 	// Define flags.
-	rootCmd.Flags().StringP("config", "c", "", "Path to configuration file")
+	rootCmd.PersistentFlags().AddFlagSet(l.persistentFlags())
 	rootCmd.Flags().BoolP("version", "v", false, "Show version info")
+	if len(l.versionFormats) > 0 {
+		rootCmd.Flags().String(versionFormatFlag, "",
+			fmt.Sprintf("Version info format (%s)", strings.Join(slices.Sorted(maps.Keys(l.versionFormats)), "|")))
+	}
+	if l.checkConfig {
+		rootCmd.Flags().Bool("check-config", false, "Load and validate configuration, then exit")
+	}
+	if l.checkOnly {
+		rootCmd.Flags().Bool(checkOnlyFlag, false, "Load and validate configuration, print the diagnostics as JSON, then exit")
+	}
+	if l.printConfig {
+		rootCmd.Flags().Bool("print-config", false, "Print the effective configuration (secrets redacted), then exit")
+	}
 
-	// Setup viper.
-	v.SetEnvPrefix(l.envPrefix)
-	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	v.AutomaticEnv()
+	rootCmd.PersistentFlags().VisitAll(func(f *pflag.Flag) {
+		_ = rootCmd.PersistentFlags().SetAnnotation(f.Name, builtinFlagAnnotation, nil) // Flag exists.
+	})
+	_ = rootCmd.RegisterFlagCompletionFunc(setKey, l.completeSetKeys(cfg)) // Flag exists and is new.
+	if err := checkFlagCollisions(rootCmd.Commands(), rootCmd.PersistentFlags()); err != nil {
+		return nil, err
+	}
 
 	// Binding flags to viper.
-	if err := v.BindPFlag("config", rootCmd.Flags().Lookup("config")); err != nil {
-		return nil, fmt.Errorf("bind config flag: %w", err)
+	if err := l.bindPersistentFlags(v, rootCmd.PersistentFlags()); err != nil {
+		return nil, err
 	}
 	if err := v.BindPFlag("version", rootCmd.Flags().Lookup("version")); err != nil {
 		return nil, fmt.Errorf("bind version flag: %w", err)
 	}
 
 	// Pre-run hook: load config or show version.
-	rootCmd.PreRunE = func(_ *cobra.Command, _ []string) error {
+	// It is persistent, so the config is loaded for the subcommands as well.
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, _ []string) error {
+		// Built-in help command and shell completion requests don't need the config.
+		if c.Name() == "help" && c.Parent() == rootCmd || isCompletionRequest(c) {
+			return nil
+		}
+
 		// Processing -v flag preemptively.
 		if versionFlag := v.GetBool("version"); versionFlag {
+			printVersion, err := l.versionPrinter(c, printVersion)
+			if err != nil {
+				return err
+			}
 			if err := printVersion(writer); err != nil {
 				return fmt.Errorf("print version: %w", err)
 			}
 			return nil
 		}
 
-		// Setting the config. If none are set, use the value, passed directly to the loader.
-		configPath := v.GetString("config")
-		if configPath == "" {
-			configPath = l.configPath
+		for _, hook := range l.preRun {
+			if err := hook(); err != nil {
+				return fmt.Errorf("pre-run: %w", err)
+			}
 		}
 
-		v.SetConfigFile(configPath)
-		if err := v.ReadInConfig(); err != nil {
-			var notFound viper.ConfigFileNotFoundError
-			if errors.As(err, &notFound) {
-				return fmt.Errorf("config file not found at %q", configPath)
+		if c.Flags().Changed(checkOnlyFlag) {
+			// The report must be the only output, so it can be parsed. Errors are in the report and returned from Load.
+			c.SilenceUsage, c.SilenceErrors = true, true
+			return l.checkConfigOnly(v, cfg, writer)
+		}
+
+		if err := l.loadConfig(v, cfg); err != nil {
+			return err
+		}
+
+		// Printing before validation, so an invalid config can be inspected as well.
+		if c.Flags().Changed("print-config") {
+			if err := l.printEffectiveConfig(writer, v, cfg); err != nil {
+				return fmt.Errorf("print config: %w", err)
 			}
-			return fmt.Errorf("read main config at %q: %w", configPath, err)
 		}
 
-		if err := v.Unmarshal(cfg); err != nil {
-			return fmt.Errorf("unmarshal main config: %w", err)
+		if err := l.validate(cfg); err != nil {
+			return err
+		}
+		l.rememberLoad(v, cfg)
+		l.flags.remember(rootCmd.PersistentFlags())
+
+		for _, hook := range l.postRun {
+			if err := hook(); err != nil {
+				return fmt.Errorf("post-run: %w", err)
+			}
 		}
 
 		return nil
@@ -83,3 +160,147 @@ func (l *Loader) buildRootCommand(
 
 	return rootCmd, nil
 }
+
+// builtinFlagAnnotation marks the built-in persistent flags.
+const builtinFlagAnnotation = "configkit_builtin"
+
+// persistentFlags returns the built-in flags, affecting the config loading:
+// --config, --config-format, --set, --env and --env-prefix (if enabled).
+func (l *Loader) persistentFlags() *pflag.FlagSet {
+	fs := pflag.NewFlagSet(l.name, pflag.ContinueOnError)
+	fs.StringP("config", "c", "", "Path to configuration file")
+	fs.String("config-format", l.configFormat, "Format of the configuration file (e.g., yaml, json), if not inferred from its extension")
+	fs.StringArray(setKey, nil, "Override a config key, can be repeated (e.g., --set db.pool_size=20)")
+	if l.profilesEnabled {
+		fs.StringP("env", "e", "", "Config profile (e.g., dev, prod), merged on top of the main config")
+	}
+	if l.envPrefixFlag {
+		fs.String("env-prefix", "", "Override the env variables prefix")
+	}
+	return fs
+}
+
+// bindPersistentFlags binds the flags, defined by persistentFlags, to v.
+func (l *Loader) bindPersistentFlags(v *viper.Viper, fs *pflag.FlagSet) error {
+	if err := v.BindPFlag("config", fs.Lookup("config")); err != nil {
+		return fmt.Errorf("bind config flag: %w", err)
+	}
+	if err := v.BindPFlag("config-format", fs.Lookup("config-format")); err != nil {
+		return fmt.Errorf("bind config-format flag: %w", err)
+	}
+	if err := v.BindPFlag(setKey, fs.Lookup(setKey)); err != nil {
+		return fmt.Errorf("bind set flag: %w", err)
+	}
+	if l.profilesEnabled {
+		if err := v.BindPFlag("env", fs.Lookup("env")); err != nil {
+			return fmt.Errorf("bind env flag: %w", err)
+		}
+	}
+	if l.envPrefixFlag {
+		if err := v.BindPFlag("env-prefix", fs.Lookup("env-prefix")); err != nil {
+			return fmt.Errorf("bind env-prefix flag: %w", err)
+		}
+	}
+	return nil
+}
+
+// loadConfig reads all config sources into v and unmarshals the result into cfg.
+// The built-in flags are expected to be bound to v.
+func (l *Loader) loadConfig(v *viper.Viper, cfg any) error {
+	// Overriding the env prefix first, as it affects the env lookup of all keys, including the config path.
+	if prefix := v.GetString("env-prefix"); l.envPrefixFlag && !l.envDisabled && prefix != "" {
+		if err := l.validateEnvPrefix(prefix); err != nil {
+			return err
+		}
+		l.setEnvPrefix(v, prefix)
+	}
+	l.bindEnvFallbacks(v, l.builtinKeys())
+
+	// If the config name is set instead (or the loader path is empty with XDG defaults), the config file is searched by name.
+	configPath := v.GetString("config")
+	if configPath == "" && l.configName == "" {
+		configPath = l.configPath
+	}
+
+	if err := l.readConfig(v, configPath); err != nil {
+		return err
+	}
+
+	if err := l.unmarshal(v, cfg); err != nil {
+		return fmt.Errorf("unmarshal main config: %w", err)
+	}
+
+	return nil
+}
+
+// checkFlagCollisions returns an error if any of cmds (or their subcommands) defines a flag, which collides by name
+// or shorthand with a built-in flag.
+//
+// Cobra panics on a shorthand collision on execution, and a flag with the same name silently shadows the built-in one.
+func checkFlagCollisions(cmds []*cobra.Command, builtins *pflag.FlagSet) error {
+	var errs []error
+	var walk func(cmd *cobra.Command)
+	walk = func(cmd *cobra.Command) {
+		check := func(f *pflag.Flag) {
+			// Built-in flags of the previous Load calls, inherited by the subcommand.
+			if _, ok := f.Annotations[builtinFlagAnnotation]; ok {
+				return
+			}
+			builtins.VisitAll(func(builtin *pflag.Flag) {
+				switch {
+				case f.Name == builtin.Name:
+					errs = append(errs, fmt.Errorf("flag --%s of command %q collides with the built-in flag --%s",
+						f.Name, cmd.CommandPath(), builtin.Name))
+				case f.Shorthand != "" && f.Shorthand == builtin.Shorthand:
+					errs = append(errs, fmt.Errorf("flag --%s of command %q: shorthand -%s collides with the built-in flag --%s",
+						f.Name, cmd.CommandPath(), f.Shorthand, builtin.Name))
+				}
+			})
+		}
+		cmd.Flags().VisitAll(check)
+		cmd.PersistentFlags().VisitAll(check)
+		for _, sub := range cmd.Commands() {
+			walk(sub)
+		}
+	}
+	for _, cmd := range cmds {
+		walk(cmd)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("check flags: %w", err)
+	}
+	return nil
+}
+
+// helpLong returns the long description of the root command.
+// If enabled, it is followed by the list of env variables, derived from the cfg struct.
+func (l *Loader) helpLong(v *viper.Viper, cfg any) string {
+	if !l.envHelp || l.envDisabled {
+		return l.long
+	}
+
+	var keys, names []string
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		keys = append(keys, key)
+		names = append(names, cmp.Or(field.Tag.Get(envTag), l.envVarName(v, key)))
+	})
+	if len(keys) == 0 {
+		return l.long
+	}
+
+	var sb strings.Builder
+	// Cobra shows the short description if the long one is empty, so keeping it.
+	if desc := cmp.Or(l.long, l.short); desc != "" {
+		sb.WriteString(desc)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("Environment Variables:\n")
+	tw := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	for i, key := range keys {
+		fmt.Fprintf(tw, "  %s\t%s\n", names[i], key)
+	}
+	_ = tw.Flush() // Writing to strings.Builder never fails.
+
+	return strings.TrimSuffix(sb.String(), "\n")
+}
@@ -0,0 +1,36 @@
+package configkit
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// SaveConfig writes the merged config to the file at path (e.g., to persist it after a first-run setup),
+// overwriting the file if it exists. The format is inferred from the extension: yaml (yml), json, toml or env.
+//
+// Values are written as they are: secret fields are not redacted, as the file is a real config.
+// The built-in keys (e.g., config) are omitted. With WithSaveOmitDefaults, only the keys, explicitly set
+// by a config file or an env variable, are written.
+func (vs *Values) SaveConfig(path string) error {
+	// Writing from a separate instance, so neither the built-in keys nor the flags get into the file.
+	tmp := viper.NewWithOptions(vs.l.viperOptions()...)
+	if vs.l.saveOmitDefaults {
+		for _, key := range vs.SetKeys() {
+			tmp.Set(key, vs.v.Get(key))
+		}
+	} else {
+		settings := vs.v.AllSettings()
+		for _, key := range vs.l.builtinKeys() {
+			delete(settings, key)
+		}
+		if err := tmp.MergeConfigMap(settings); err != nil {
+			return fmt.Errorf("save config: %w", err)
+		}
+	}
+
+	if err := tmp.WriteConfigAs(path); err != nil {
+		return fmt.Errorf("save config to %q: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package configkit
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// setKey is the viper key of the --set flag, overriding arbitrary config keys.
+const setKey = "set"
+
+// setOverrides parses the key=value pairs of the --set flag of v into the keys and their values.
+// The values are typed according to inferSetValue. Later pairs for the same key win.
+func (l *Loader) setOverrides(v *viper.Viper) (keys []string, values map[string]any, err error) {
+	values = make(map[string]any)
+	for _, pair := range v.GetStringSlice(setKey) {
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !ok || key == "" {
+			return nil, nil, fmt.Errorf("invalid --set %q: expected key=value", pair)
+		}
+		if _, seen := values[key]; !seen {
+			keys = append(keys, key)
+		}
+		values[key] = inferSetValue(value)
+	}
+	return keys, values, nil
+}
+
+// applySetOverrides sets the values of the --set flag on v, so they take precedence over all other sources.
+func (l *Loader) applySetOverrides(v *viper.Viper) error {
+	keys, values, err := l.setOverrides(v)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		v.Set(key, values[key])
+		l.log().Debug("config key set by flag", "key", key)
+	}
+	return nil
+}
+
+// completeSetKeys returns the shell completion of the --set flag, suggesting the config keys of cfg (see ConfigKeys)
+// as key= (e.g., db.pool_size=). Map fields are suggested up to the entry name (e.g., labels.), so it may be typed in.
+// Nothing is suggested for the value part.
+func (l *Loader) completeSetKeys(cfg any) cobra.CompletionFunc {
+	return func(_ *cobra.Command, _ []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if strings.Contains(toComplete, "=") {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		var suggestions []cobra.Completion
+		for _, key := range l.ConfigKeys(cfg) {
+			suggestion := key + "="
+			if before, _, ok := strings.Cut(key, mapKeyPlaceholder); ok {
+				suggestion = before
+			}
+			if strings.HasPrefix(suggestion, toComplete) && !slices.Contains(suggestions, suggestion) {
+				suggestions = append(suggestions, suggestion)
+			}
+		}
+		return suggestions, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completionCmdName is the name of the cobra command, generating the shell completion scripts.
+const completionCmdName = "completion"
+
+// isCompletionRequest reports whether c is the hidden command of cobra, requesting the shell completion choices,
+// or the completion command (or its per-shell subcommand), generating the script. They don't need the config.
+func isCompletionRequest(c *cobra.Command) bool {
+	return c.Name() == cobra.ShellCompRequestCmd || c.Name() == completionCmdName ||
+		c.HasParent() && c.Parent().Name() == completionCmdName
+}
+
+// inferSetValue returns the value of the --set pair as a bool ("true" or "false"), an int or a float,
+// if it is the canonical form of one (e.g., "20", but not "020"), so no information is lost. Otherwise,
+// the value is returned as a string: e.g., a string field still gets "020" as is.
+func inferSetValue(value string) any {
+	switch value {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(value); err == nil && strconv.Itoa(i) == value {
+		return i
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == value {
+		return f
+	}
+	return value
+}
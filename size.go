@@ -0,0 +1,102 @@
+package configkit
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// ByteSize is a size in bytes, decoded from a human-readable string (e.g., "10MB", "1.5GiB") as well as a number.
+//
+// Decimal units (KB, MB, GB, TB, PB) are powers of 1000, binary ones (KiB, MiB, GiB, TiB, PiB) are powers of 1024.
+// Units are case-insensitive. A number without a unit (or with B) is a number of bytes.
+type ByteSize int64
+
+// sizeTag marks the integer fields, decoded from human-readable sizes the same way ByteSize is (e.g., `size:"true"`).
+const sizeTag = "size"
+
+// sizeUnits maps the lowercased size units to the number of bytes.
+var sizeUnits = map[string]int64{
+	"": 1, "b": 1,
+	"kb": 1e3, "mb": 1e6, "gb": 1e9, "tb": 1e12, "pb": 1e15,
+	"kib": 1 << 10, "mib": 1 << 20, "gib": 1 << 30, "tib": 1 << 40, "pib": 1 << 50,
+}
+
+// parseByteSize parses the human-readable size s (e.g., "10MB") into the number of bytes.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+	num, unit := s[:i], strings.TrimSpace(s[i:])
+
+	mult, ok := sizeUnits[strings.ToLower(unit)]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q (expected B, KB, MB, GB, TB, PB, KiB, MiB, GiB, TiB or PiB)", s, unit)
+	}
+
+	if !strings.Contains(num, ".") {
+		// Parsing integers as is, so the large sizes don't lose precision.
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid size %q: %w", s, err)
+		}
+		if n > math.MaxInt64/mult {
+			return 0, fmt.Errorf("invalid size %q: out of range", s)
+		}
+		return n * mult, nil
+	}
+
+	f, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q: %w", s, err)
+	}
+	size := f * float64(mult)
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("invalid size %q: out of range", s)
+	}
+	return int64(size), nil
+}
+
+// stringToByteSizeHook returns a decode hook, parsing strings into ByteSize values.
+func stringToByteSizeHook() mapstructure.DecodeHookFuncType {
+	return func(from, to reflect.Type, data any) (any, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(ByteSize(0)) {
+			return data, nil
+		}
+		size, err := parseByteSize(data.(string))
+		if err != nil {
+			return nil, err
+		}
+		return ByteSize(size), nil
+	}
+}
+
+// applySizes parses the string values of the integer fields of cfg, tagged with `size:"true"`,
+// as human-readable sizes. Parsed values are set on v, so the decoder receives them as numbers.
+func (l *Loader) applySizes(v *viper.Viper, cfg any) error {
+	var err error
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		if field.Tag.Get(sizeTag) != "true" || err != nil {
+			return
+		}
+		raw, ok := v.Get(key).(string)
+		if !ok {
+			return // Not set or already a number.
+		}
+
+		size, parseErr := parseByteSize(raw)
+		if parseErr != nil {
+			err = fmt.Errorf("'%s' parse size: %w", key, parseErr)
+			return
+		}
+		v.Set(key, size)
+	})
+	return err
+}
@@ -0,0 +1,72 @@
+package configkit
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// Source is a custom config source (e.g., a database or a feature flag service), registered with WithSources.
+//
+// The sources are read on each load and merged with the config files by priority: the ones with a negative
+// priority are merged under the config files (as defaults), the rest on top of them. Among the sources,
+// higher priority wins; on a tie, the source registered later wins. Env variables and flags override all sources.
+type Source interface {
+	// Read returns the config values: nested keys are nested maps (e.g., {"db": {"pool_size": 10}}).
+	Read() (map[string]any, error)
+
+	// Priority returns the priority of the source relative to the other sources and the config files.
+	Priority() int
+}
+
+// mapSource is an in-memory Source, serving a fixed map.
+type mapSource struct {
+	values   map[string]any
+	priority int
+}
+
+// NewMapSource returns an in-memory Source, serving values with the given priority (see Source).
+// It is handy for tests and for the values, computed by the application at startup.
+// values is not copied: changes to it are picked up by the next load.
+func NewMapSource(values map[string]any, priority int) Source {
+	return mapSource{values: values, priority: priority}
+}
+
+// Read returns the values of the source.
+func (s mapSource) Read() (map[string]any, error) {
+	return s.values, nil
+}
+
+// Priority returns the priority of the source.
+func (s mapSource) Priority() int {
+	return s.priority
+}
+
+// mergeSources merges the custom sources, which priority is under (or, if above is true, at or over) zero, into v,
+// in the order of increasing priority, so the higher ones override the lower ones.
+func (l *Loader) mergeSources(v *viper.Viper, above bool) error {
+	var sources []Source
+	for _, source := range l.sources {
+		if source != nil && (source.Priority() >= 0) == above {
+			sources = append(sources, source)
+		}
+	}
+	// Stable, so the sources of the same priority are merged in the order they were registered in.
+	slices.SortStableFunc(sources, func(a, b Source) int {
+		return a.Priority() - b.Priority()
+	})
+
+	for _, source := range sources {
+		values, err := source.Read()
+		if err != nil {
+			return fmt.Errorf("read config source %T: %w", source, err)
+		}
+		// Copying, as viper lowercases the keys of the merged map in place.
+		if err := v.MergeConfigMap(transformMapKeys(copySettings(values), l.keyTransform)); err != nil {
+			return fmt.Errorf("merge config source %T: %w", source, err)
+		}
+		l.log().Debug("merged config source", "source", fmt.Sprintf("%T", source), "priority", source.Priority())
+	}
+	return nil
+}
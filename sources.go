@@ -0,0 +1,102 @@
+package configkit
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/spf13/viper"
+)
+
+// KeySource is the source of a config key value.
+type KeySource string
+
+const (
+	// KeySourceDefault means the key is known, but no source sets its value (e.g. an unset WithEnvBindings key).
+	KeySourceDefault KeySource = "default"
+
+	// KeySourceFile means the value comes from the config sources: config files, embedded default, remote config
+	// or custom sources (see Source).
+	KeySourceFile KeySource = "file"
+
+	// KeySourceEnv means the value comes from an env variable.
+	KeySourceEnv KeySource = "env"
+
+	// KeySourceFlag means the value comes from a command line flag.
+	KeySourceFlag KeySource = "flag"
+)
+
+// KeySources returns all the keys of v along with the sources of their values.
+//
+// v is expected to be the instance, returned by LoadWithViper of the same Loader. Env variables, bound
+// by the `env:"NAME"` tags, are reported as KeySourceEnv.
+// If a key is set by several sources, the one with the highest priority is reported.
+// Keys of the built-in flags (e.g. "config") are reported only if set. If both the flag
+// and its env variable are set, KeySourceEnv is reported.
+func (l *Loader) KeySources(v *viper.Viper) map[string]KeySource {
+	builtin := l.builtinKeys()
+	_, set, _ := l.setOverrides(v) // A malformed --set fails the load before the sources may be reported.
+	sources := make(map[string]KeySource)
+	for _, key := range v.AllKeys() {
+		if _, ok := set[key]; ok {
+			sources[key] = KeySourceFlag
+			continue
+		}
+
+		name := l.envVarName(v, key)
+		_, tagSet := l.envTagName(key)
+		envSet := !l.envDisabled && (tagSet || l.envSet(name) || l.getenv(name+envFileSuffix) != "" || l.jsonEnvSet(v, key) ||
+			slices.ContainsFunc(l.fallbackPrefixes, func(prefix string) bool {
+				return l.envSet(l.prefixedEnvName(prefix, key))
+			}))
+
+		if slices.Contains(builtin, key) {
+			// Built-in keys are set either by flag or by env. IsSet reports flags only if they were changed.
+			switch {
+			case !v.IsSet(key):
+			case envSet:
+				sources[key] = KeySourceEnv
+			default:
+				sources[key] = KeySourceFlag
+			}
+			continue
+		}
+
+		switch {
+		case envSet:
+			sources[key] = KeySourceEnv
+		case v.InConfig(key):
+			sources[key] = KeySourceFile
+		default:
+			sources[key] = KeySourceDefault
+		}
+	}
+	return sources
+}
+
+// logOverrides logs the keys of v, overridden by env variables or flags, in sorted order, along with their values.
+// Values of the secret fields of cfg (and of the keys, nested in them), as well as of --set itself,
+// are replaced with a placeholder.
+func (l *Loader) logOverrides(v *viper.Viper, cfg any) {
+	secrets := l.secretKeys(v, cfg)
+	sources := l.KeySources(v)
+	keys := slices.Sorted(maps.Keys(sources))
+	for _, key := range keys {
+		// The pairs of --set may hold secrets too, and they are logged per key anyway.
+		var value any = redactedValue
+		if key != setKey && !isSecretKey(key, secrets, l.keyDelimiter) {
+			value = v.Get(key)
+		}
+
+		switch source := sources[key]; source {
+		case KeySourceEnv:
+			// The tagged variable takes precedence over the derived one.
+			name, ok := l.envTagName(key)
+			if !ok {
+				name = l.envVarName(v, key)
+			}
+			l.log().Debug("config key overridden", "key", key, "source", source, "env", name, "value", value)
+		case KeySourceFlag:
+			l.log().Debug("config key overridden", "key", key, "source", source, "value", value)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package configkit
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/spf13/viper"
+)
+
+// timeFormatTag overrides the layout of a time.Time field (e.g., `timeformat:"2006-01-02"`).
+// Fields without the tag are parsed with the default layouts (see timeLayouts).
+const timeFormatTag = "timeformat"
+
+// applyTimeFormats parses the string values of the time.Time fields of cfg, tagged with timeformat,
+// using their layouts. Parsed values are set on v, so the decoder receives them as time.Time.
+func (l *Loader) applyTimeFormats(v *viper.Viper, cfg any) error {
+	var err error
+	walkStructKeys(reflect.TypeOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField) {
+		layout, ok := field.Tag.Lookup(timeFormatTag)
+		if !ok || err != nil {
+			return
+		}
+		raw, ok := v.Get(key).(string)
+		if !ok {
+			return // Not set or already decoded by the config format (e.g., TOML datetime).
+		}
+
+		t, parseErr := time.Parse(layout, raw)
+		if parseErr != nil {
+			err = fmt.Errorf("'%s' parse time %q with layout %q: %w", key, raw, layout, parseErr)
+			return
+		}
+		v.Set(key, t)
+	})
+	return err
+}
+
+// timeLayouts are the layouts of the time.Time fields without the timeformat tag, tried in order:
+// RFC3339, as well as date-only and local date-time (in UTC), which YAML and TOML allow unquoted.
+var timeLayouts = []string{time.RFC3339, time.DateOnly, "2006-01-02T15:04:05"}
+
+// localTime is implemented by the local date and date-time types of the TOML decoder (e.g., `date = 2024-01-02`).
+type localTime interface {
+	AsTime(zone *time.Location) time.Time
+}
+
+// timeType is the reflective type of time.Time.
+var timeType = reflect.TypeOf(time.Time{})
+
+// stringToTimeHook returns a decode hook, parsing strings into time.Time values with the timeLayouts.
+// TOML local dates and date-times are converted to time.Time in UTC, so the same value decodes the same way
+// regardless of the config format.
+func stringToTimeHook() mapstructure.DecodeHookFuncType {
+	return func(_ reflect.Type, to reflect.Type, data any) (any, error) {
+		if to != timeType {
+			return data, nil
+		}
+		switch data := data.(type) {
+		case localTime:
+			return data.AsTime(time.UTC), nil
+		case string:
+			for _, layout := range timeLayouts {
+				if t, err := time.Parse(layout, data); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("parse time %q: expected RFC3339, date-only (2006-01-02) or local date-time (2006-01-02T15:04:05)", data)
+		default:
+			return data, nil
+		}
+	}
+}
+
+// durationType is the reflective type of time.Duration.
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// numberToDurationHook returns a decode hook, converting floats (e.g., a JSON number) into time.Duration
+// as nanoseconds, the way Go represents durations. Integers are converted by mapstructure as is.
+// Strings (e.g., "30s" or an env value) are left to the viper's duration hook, so a numeric string without
+// a unit (e.g., "30") is an error rather than silently taken as nanoseconds.
+func numberToDurationHook() mapstructure.DecodeHookFuncType {
+	return func(_ reflect.Type, to reflect.Type, data any) (any, error) {
+		if to != durationType {
+			return data, nil
+		}
+		switch data := data.(type) {
+		case float32:
+			return time.Duration(data), nil
+		case float64:
+			return time.Duration(data), nil
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,49 @@
+package configkit
+
+import (
+	"reflect"
+	"strings"
+)
+
+// trimTag opts a string field out of whitespace trimming (`trim:"false"`), see WithTrimSpace.
+const trimTag = "trim"
+
+// trimStrings trims the leading and trailing whitespace from the string fields of cfg,
+// including pointers to strings, string slices and maps, and the fields of map[string]Struct entries.
+// Fields, tagged with `trim:"false"`, are left as is.
+func (l *Loader) trimStrings(cfg any) {
+	walkStructValues(reflect.ValueOf(cfg), "", l.keyFormat(), func(_ string, field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get(trimTag) != "false" {
+			trimValue(value)
+		}
+	})
+}
+
+// trimValue trims the string value in place, following pointers, slice elements and map values.
+func trimValue(value reflect.Value) {
+	switch value.Kind() {
+	case reflect.String:
+		if value.CanSet() {
+			value.SetString(strings.TrimSpace(value.String()))
+		}
+	case reflect.Ptr:
+		if !value.IsNil() {
+			trimValue(value.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range value.Len() {
+			trimValue(value.Index(i))
+		}
+	case reflect.Map:
+		if !value.CanSet() {
+			return
+		}
+		// Map values can't be set in place: trimming a copy and storing it back.
+		for _, key := range value.MapKeys() {
+			elem := reflect.New(value.Type().Elem()).Elem()
+			elem.Set(value.MapIndex(key))
+			trimValue(elem)
+			value.SetMapIndex(key, elem)
+		}
+	}
+}
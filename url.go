@@ -0,0 +1,130 @@
+package configkit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// defaultHTTPTimeout limits fetching a config by URL with the default HTTP client.
+const defaultHTTPTimeout = 10 * time.Second
+
+// contentTypeFormats maps the media types of the config responses to the config formats.
+var contentTypeFormats = map[string]string{
+	"application/json":   "json",
+	"application/yaml":   "yaml",
+	"application/x-yaml": "yaml",
+	"text/yaml":          "yaml",
+	"text/x-yaml":        "yaml",
+	"application/toml":   "toml",
+	"text/toml":          "toml",
+}
+
+// isConfigURL reports whether the config path is an http(s) URL (e.g., https://config.local/app.yaml).
+func isConfigURL(p string) bool {
+	lower := strings.ToLower(p)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// redactConfigPath returns the config path with the password of the URL (if it is one) masked, to be reported.
+func redactConfigPath(p string) string {
+	if !isConfigURL(p) {
+		return p
+	}
+	if u, err := url.Parse(p); err == nil {
+		return u.Redacted()
+	}
+	return p
+}
+
+// httpClient returns the client to fetch the configs by URL with: the WithHTTPClient one,
+// or the default one with defaultHTTPTimeout.
+func (l *Loader) httpClient() *http.Client {
+	if l.client != nil {
+		return l.client
+	}
+	return &http.Client{Timeout: defaultHTTPTimeout}
+}
+
+// mergeURL fetches the config at rawURL and merges it into v, decoding it as format.
+//
+// If format is empty, it is inferred from the extension of the URL path, or from the Content-Type of the response
+// otherwise. A 404 response results in fs.ErrNotExist, the way a missing file does. Other non-200 responses,
+// as well as network errors (e.g., a timeout or a TLS error), fail the loading.
+func (l *Loader) mergeURL(v *viper.Viper, rawURL, format string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse config URL: %w", err)
+	}
+	redacted := u.Redacted() // Passwords, if any, are not reported.
+
+	resp, err := l.httpClient().Get(u.String())
+	if err != nil {
+		return fmt.Errorf("fetch config from %q: %w", redacted, unwrapURLError(err))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("fetch config from %q: %s: %w", redacted, resp.Status, fs.ErrNotExist)
+	case resp.StatusCode != http.StatusOK:
+		return fmt.Errorf("fetch config from %q: unexpected status %s", redacted, resp.Status)
+	}
+
+	var body io.Reader = resp.Body
+	if l.maxConfigSize > 0 {
+		// Reading one byte over the limit to tell the config of the limit size from a larger one.
+		body = io.LimitReader(resp.Body, l.maxConfigSize+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return fmt.Errorf("fetch config from %q: read body: %w", redacted, err)
+	}
+	if l.maxConfigSize > 0 && int64(len(data)) > l.maxConfigSize {
+		return fmt.Errorf("fetch config from %q: config exceeds the limit of %d bytes", redacted, l.maxConfigSize)
+	}
+
+	if format == "" {
+		if format, err = urlFormat(u, resp.Header.Get("Content-Type")); err != nil {
+			return fmt.Errorf("fetch config from %q: %w", redacted, err)
+		}
+	}
+	if err := l.mergeData(v, data, format); err != nil {
+		return asParseError(redacted, err)
+	}
+	l.log().Debug("read config from URL", "url", redacted, "format", format)
+
+	return nil
+}
+
+// urlFormat returns the format of the config at u, inferred from the extension of its path,
+// or from the contentType of the response, if the path has no supported extension.
+func urlFormat(u *url.URL, contentType string) (string, error) {
+	if format := strings.ToLower(strings.TrimPrefix(path.Ext(u.Path), ".")); isSupportedFormat(format) {
+		return format, nil
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if format, ok := contentTypeFormats[mediaType]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("can't infer the config format from the URL path or Content-Type %q"+
+		" - set the format with --config-format (or WithConfigFormat)", contentType)
+}
+
+// unwrapURLError returns the cause of the *url.Error, as it repeats the method and the URL, reported by the caller.
+func unwrapURLError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
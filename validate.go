@@ -0,0 +1,120 @@
+package configkit
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// requiredTag marks config fields, which must be set to a non-zero value (e.g., `required:"true"`).
+const requiredTag = "required"
+
+// oneOfTag puts config fields into a group, exactly one alternative of which must be set (e.g., `oneof:"auth"`).
+// Fields, sharing the alternative name (e.g., `oneof:"auth:basic"`), form a single alternative and must be set together.
+const oneOfTag = "oneof"
+
+// validate checks the required fields and oneof groups of the loaded cfg and runs the registered validators against it.
+// All problems are reported at once, joined into a single error.
+func (l *Loader) validate(cfg any) error {
+	errs := append(l.checkRequired(cfg), l.checkOneOf(cfg)...)
+	for _, validate := range l.validators {
+		if err := validate(cfg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("validate config: %w", err)
+	}
+	return nil
+}
+
+// checkRequired returns an error for each field of cfg, tagged with `required:"true"` and left zero.
+func (l *Loader) checkRequired(cfg any) []error {
+	var errs []error
+	walkStructValues(reflect.ValueOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField, value reflect.Value) {
+		if field.Tag.Get(requiredTag) == "true" && value.IsZero() {
+			errs = append(errs, &FieldError{Key: key, Err: fmt.Errorf("'%s' is required", key)})
+		}
+	})
+	return errs
+}
+
+// oneOfGroup holds the alternatives of a oneof group in the order of declaration.
+type oneOfGroup struct {
+	name   string              // Group name, as tagged.
+	alts   []string            // Alternative names.
+	fields map[string][]string // Alternative name → its config keys.
+	set    map[string][]string // Alternative name → its non-zero config keys.
+}
+
+// checkOneOf returns an error for each oneof group of cfg, which has none or several alternatives set,
+// or an alternative, set partially.
+//
+// Groups are scoped to the enclosing struct: the same group of different structs (e.g., of the entries
+// of a map[string]Struct field) is checked separately.
+func (l *Loader) checkOneOf(cfg any) []error {
+	var scopes []string
+	groups := make(map[string]*oneOfGroup) // Enclosing struct key prefix + group name → group.
+	walkStructValues(reflect.ValueOf(cfg), "", l.keyFormat(), func(key string, field reflect.StructField, value reflect.Value) {
+		tag, ok := field.Tag.Lookup(oneOfTag)
+		if !ok {
+			return
+		}
+		name, alt, ok := strings.Cut(tag, ":")
+		if !ok {
+			alt = key // A field on its own.
+		}
+
+		scope := name
+		if i := strings.LastIndex(key, l.keyDelimiter); i >= 0 {
+			scope = key[:i+len(l.keyDelimiter)] + name
+		}
+		group := groups[scope]
+		if group == nil {
+			group = &oneOfGroup{name: name, fields: make(map[string][]string), set: make(map[string][]string)}
+			groups[scope] = group
+			scopes = append(scopes, scope)
+		}
+		if _, ok := group.fields[alt]; !ok {
+			group.alts = append(group.alts, alt)
+		}
+		group.fields[alt] = append(group.fields[alt], key)
+		if !value.IsZero() {
+			group.set[alt] = append(group.set[alt], key)
+		}
+	})
+
+	var errs []error
+	for _, scope := range scopes {
+		group := groups[scope]
+		name := group.name
+		all := make([]string, 0, len(group.alts))
+		var set []string
+		for _, alt := range group.alts {
+			all = append(all, strings.Join(group.fields[alt], "+"))
+			if len(group.set[alt]) > 0 {
+				set = append(set, strings.Join(group.set[alt], "+"))
+			}
+			if n := len(group.set[alt]); n > 0 && n < len(group.fields[alt]) {
+				missing := slices.DeleteFunc(slices.Clone(group.fields[alt]), func(key string) bool {
+					return slices.Contains(group.set[alt], key)
+				})
+				errs = append(errs, fmt.Errorf("oneof group %q: %s must be set together, missing: %s",
+					name, strings.Join(group.fields[alt], "+"), strings.Join(missing, ", ")))
+			}
+		}
+
+		switch len(set) {
+		case 0:
+			errs = append(errs, fmt.Errorf("oneof group %q: exactly one of %s must be set, got none", name, strings.Join(all, ", ")))
+		case 1:
+		default:
+			errs = append(errs, fmt.Errorf("oneof group %q: exactly one of %s must be set, got %s",
+				name, strings.Join(all, ", "), strings.Join(set, ", ")))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,105 @@
+// Package validation integrates go-playground/validator struct tags with configkit.
+//
+// It is a separate package, so the validator dependency is only pulled in when actually used.
+//
+// Example:
+//
+//	type Config struct {
+//	    Port int `mapstructure:"port" validate:"required,min=1"`
+//	}
+//
+//	loader := configkit.NewLoader("myapp", "My App", "", "config.yaml", "MYAPP",
+//	    validation.WithStructValidation("mapstructure"),
+//	)
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Averlex/configkit"
+	"github.com/go-playground/validator/v10"
+)
+
+// WithStructValidation returns a configkit option, which validates the loaded config
+// against its `validate:"..."` struct tags.
+//
+// All field errors are aggregated into a single error. Fields are referred to by their config keys,
+// e.g. "db.url". tagName is the struct tag with the config key names, the same one configkit uses
+// (see configkit.WithTagName); an empty tagName means "mapstructure".
+func WithStructValidation(tagName string) configkit.Option {
+	if tagName == "" {
+		tagName = "mapstructure"
+	}
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	validate.RegisterTagNameFunc(configKey(tagName))
+	return configkit.WithValidator(func(cfg any) error {
+		return Struct(validate, cfg)
+	})
+}
+
+// Struct validates cfg with validate and aggregates field errors into a single readable error.
+// Each field error is a *configkit.FieldError, carrying the config key of the field.
+func Struct(validate *validator.Validate, cfg any) error {
+	err := validate.Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return err
+	}
+
+	errs := make([]error, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		path := fieldPath(fe.Namespace())
+		errs = append(errs, &configkit.FieldError{Key: path, Err: fmt.Errorf("%s: failed on %q", path, constraint(fe))})
+	}
+	return errors.Join(errs...)
+}
+
+// squashedName stands for the squashed fields in the namespaces. It is never a config key,
+// as the tag name ends at the first comma.
+const squashedName = ",squash"
+
+// configKey returns a function, which reports the config key of the field:
+// its tagName tag name or the lowercased field name, as configkit does.
+// Squashed fields are reported as squashedName, dropped from the path by fieldPath.
+func configKey(tagName string) func(f reflect.StructField) string {
+	return func(f reflect.StructField) string {
+		name, opts, _ := strings.Cut(f.Tag.Get(tagName), ",")
+		if slices.Contains(strings.Split(opts, ","), "squash") {
+			return squashedName
+		}
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return strings.ToLower(f.Name)
+		}
+		return name
+	}
+}
+
+// fieldPath strips the root struct name and the squashed fields from the namespace:
+// "Config.db.url" → "db.url", "Config.,squash.port" → "port".
+func fieldPath(namespace string) string {
+	_, path, ok := strings.Cut(namespace, ".")
+	if !ok {
+		return namespace
+	}
+	segments := slices.DeleteFunc(strings.Split(path, "."), func(s string) bool { return s == squashedName })
+	return strings.Join(segments, ".")
+}
+
+// constraint returns the failed constraint with its parameter, if any: "min=1".
+func constraint(fe validator.FieldError) string {
+	if fe.Param() == "" {
+		return fe.Tag()
+	}
+	return fe.Tag() + "=" + fe.Param()
+}
@@ -0,0 +1,175 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Averlex/configkit"
+	"github.com/stretchr/testify/suite"
+)
+
+type ValidationSuite struct {
+	suite.Suite
+}
+
+func TestValidationSuite(t *testing.T) {
+	suite.Run(t, new(ValidationSuite))
+}
+
+func (s *ValidationSuite) TestWithStructValidation() {
+	type testConfig struct {
+		Port int `mapstructure:"port" validate:"required,min=1"`
+		DB   struct {
+			URL string `mapstructure:"url" validate:"required"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name          string
+		content       string
+		expected      configkit.LoadResult
+		expectedError []string
+	}{
+		{
+			name:     "valid config",
+			content:  "port: 8080\ndb:\n  url: localhost:5432\n",
+			expected: configkit.LoadResultContinue,
+		},
+		{
+			name:          "port below min",
+			content:       "port: -1\ndb:\n  url: localhost:5432\n",
+			expected:      configkit.LoadResultStop,
+			expectedError: []string{`port: failed on "min=1"`},
+		},
+		{
+			name:          "all errors aggregated",
+			content:       "db:\n  url: \"\"\n",
+			expected:      configkit.LoadResultStop,
+			expectedError: []string{`port: failed on "required"`, `db.url: failed on "required"`},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := filepath.Join(s.T().TempDir(), "config.yaml")
+			s.Require().NoError(os.WriteFile(configPath, []byte(tC.content), 0o600), "write config file")
+
+			loader := configkit.NewLoader("testapp", "Test App", "", configPath, "TESTAPP", WithStructValidation("mapstructure"))
+			result, err := loader.LoadArgs(nil, &testConfig{}, configkit.PlainVersionPrinter("v1.0.0"), &bytes.Buffer{})
+
+			if len(tC.expectedError) > 0 {
+				s.Require().Error(err, "expected error, got nil")
+				for _, msg := range tC.expectedError {
+					s.Require().ErrorContains(err, msg, "unexpected error")
+				}
+			} else {
+				s.Require().NoError(err, "expected nil, got error")
+			}
+			s.Require().Equal(tC.expected, result, "unexpected load result: got %v, want %v", result, tC.expected)
+		})
+	}
+}
+
+func (s *ValidationSuite) TestWithStructValidation_ConfigCheckOnly() {
+	type testConfig struct {
+		Port int `mapstructure:"port" validate:"required,min=1"`
+		DB   struct {
+			URL string `mapstructure:"url" validate:"required"`
+		} `mapstructure:"db"`
+	}
+
+	configPath := filepath.Join(s.T().TempDir(), "config.yaml")
+	s.Require().NoError(os.WriteFile(configPath, []byte("port: -1\n"), 0o600), "write config file")
+
+	loader := configkit.NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+		WithStructValidation("mapstructure"), configkit.WithConfigCheckOnly())
+	var buf bytes.Buffer
+	_, err := loader.LoadArgs([]string{"--config-check-only"}, &testConfig{}, configkit.PlainVersionPrinter("v1.0.0"), &buf)
+	s.Require().Error(err, "expected error, got nil")
+
+	var report struct {
+		Errors []configkit.ReportError `json:"errors"`
+	}
+	s.Require().NoError(json.Unmarshal(buf.Bytes(), &report), "unmarshal report")
+	expected := []configkit.ReportError{
+		{Key: "port", Message: `port: failed on "min=1"`},
+		{Key: "db.url", Message: `db.url: failed on "required"`},
+	}
+	s.Require().Equal(expected, report.Errors, "unexpected errors")
+}
+
+func (s *ValidationSuite) TestWithStructValidation_Keys() {
+	type jsonConfig struct {
+		Port int `json:"http_port" validate:"required"`
+		DB   struct {
+			URL string `json:"dsn" validate:"required"`
+		} `json:"database"`
+	}
+	type untaggedConfig struct {
+		Port int `validate:"required"`
+		DB   struct {
+			URL string `validate:"required"`
+		}
+	}
+	type baseConfig struct {
+		Port int `mapstructure:"port" validate:"min=1"`
+	}
+	type squashedConfig struct {
+		baseConfig `mapstructure:",squash"`
+		DB         struct {
+			Base baseConfig `mapstructure:",squash"`
+		} `mapstructure:"db"`
+	}
+
+	testCases := []struct {
+		name     string
+		tagName  string
+		cfg      any
+		expected []string
+	}{
+		{
+			name:     "custom tag name",
+			tagName:  "json",
+			cfg:      &jsonConfig{},
+			expected: []string{"http_port", "database.dsn"},
+		},
+		{
+			name:     "untagged fields",
+			tagName:  "mapstructure",
+			cfg:      &untaggedConfig{},
+			expected: []string{"port", "db.url"},
+		},
+		{
+			name:     "squashed structs",
+			tagName:  "mapstructure",
+			cfg:      &squashedConfig{},
+			expected: []string{"port", "db.port"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			configPath := filepath.Join(s.T().TempDir(), "config.yaml")
+			s.Require().NoError(os.WriteFile(configPath, []byte("{}\n"), 0o600), "write config file")
+
+			loader := configkit.NewLoader("testapp", "Test App", "", configPath, "TESTAPP",
+				configkit.WithTagName(tC.tagName), WithStructValidation(tC.tagName), configkit.WithConfigCheckOnly())
+			var buf bytes.Buffer
+			_, err := loader.LoadArgs([]string{"--config-check-only"}, tC.cfg, configkit.PlainVersionPrinter("v1.0.0"), &buf)
+			s.Require().Error(err, "expected error, got nil")
+
+			var report struct {
+				Errors []configkit.ReportError `json:"errors"`
+			}
+			s.Require().NoError(json.Unmarshal(buf.Bytes(), &report), "unmarshal report")
+			keys := make([]string, 0, len(report.Errors))
+			for _, e := range report.Errors {
+				keys = append(keys, e.Key)
+			}
+			s.Require().Equal(tC.expected, keys, "unexpected error keys")
+		})
+	}
+}
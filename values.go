@@ -0,0 +1,84 @@
+package configkit
+
+import (
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Values provides typed read-only access to the merged config by keys (e.g., "db.pool_size").
+//
+// It lets the parts of the application (e.g., plugins) read their own keys without sharing the config struct.
+// Values are looked up in all the config sources with the same priorities as Load uses.
+// Missing keys and values, which can't be converted to the requested type, result in zero values.
+type Values struct {
+	v       *viper.Viper
+	l       *Loader
+	sources map[string]KeySource // Sources of the config keys at the load time, except for the built-in ones.
+}
+
+// LoadValues acts like Load, but also returns the typed accessor over the merged config.
+// Values are nil if an error occurred. On --help and --version the config is not read, so no values are available.
+func (l *Loader) LoadValues(cfg any, printVersion func(io.Writer) error, writer io.Writer) (*Values, LoadResult, error) {
+	v, result, err := l.LoadWithViper(cfg, printVersion, writer)
+	if err != nil {
+		return nil, result, err
+	}
+
+	sources := l.KeySources(v)
+	for _, key := range l.builtinKeys() {
+		delete(sources, key)
+	}
+	return &Values{v: v, l: l, sources: sources}, result, nil
+}
+
+// IsSet reports whether the key is set in any of the config sources.
+func (vs *Values) IsSet(key string) bool {
+	return vs.v.IsSet(key)
+}
+
+// FieldWasSet reports whether the leaf key (e.g., "db.url") was explicitly provided by a config file
+// (including the embedded default and remote config) or an env variable.
+//
+// Unlike IsSet, keys holding only default values (e.g., of the default tags) are reported as not set.
+// It suits the "apply only what was set" semantics, e.g. merging the config into an existing runtime one.
+func (vs *Values) FieldWasSet(key string) bool {
+	source, ok := vs.sources[strings.ToLower(key)]
+	return ok && source != KeySourceDefault
+}
+
+// SetKeys returns the sorted leaf keys, explicitly provided by the config sources (see FieldWasSet).
+func (vs *Values) SetKeys() []string {
+	keys := make([]string, 0, len(vs.sources))
+	for _, key := range slices.Sorted(maps.Keys(vs.sources)) {
+		if vs.sources[key] != KeySourceDefault {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// GetString returns the value of the key as a string.
+func (vs *Values) GetString(key string) string {
+	return vs.v.GetString(key)
+}
+
+// GetInt returns the value of the key as an int.
+func (vs *Values) GetInt(key string) int {
+	return vs.v.GetInt(key)
+}
+
+// GetDuration returns the value of the key as a time.Duration (e.g., parsed from "5s").
+func (vs *Values) GetDuration(key string) time.Duration {
+	return vs.v.GetDuration(key)
+}
+
+// GetStringSlice returns the value of the key as a slice of strings.
+// A string value (e.g., from an env variable) is split by whitespace.
+func (vs *Values) GetStringSlice(key string) []string {
+	return vs.v.GetStringSlice(key)
+}
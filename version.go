@@ -1,11 +1,46 @@
 package configkit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
+	"maps"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
+// versionFormatFlag is the flag, selecting the version printer among the WithVersionFormats ones.
+const versionFormatFlag = "version-format"
+
+// versionPrinter returns the version printer, selected by the --version-format flag of c,
+// or printVersion, if the flag is not set.
+func (l *Loader) versionPrinter(c *cobra.Command, printVersion func(io.Writer) error) (func(io.Writer) error, error) {
+	if !c.Flags().Changed(versionFormatFlag) {
+		return printVersion, nil
+	}
+	format, _ := c.Flags().GetString(versionFormatFlag) // Flag exists, if changed.
+	printer, ok := l.versionFormats[format]
+	if !ok || printer == nil {
+		return nil, fmt.Errorf("unknown version format %q: expected one of %s",
+			format, strings.Join(slices.Sorted(maps.Keys(l.versionFormats)), ", "))
+	}
+	return printer, nil
+}
+
+// versionInfo is a structured version info, shared by the structured version printers.
+type versionInfo struct {
+	Version string `json:"version" yaml:"version"`
+	Commit  string `json:"commit,omitempty" yaml:"commit,omitempty"`
+	Date    string `json:"date,omitempty" yaml:"date,omitempty"`
+}
+
 // PlainVersionPrinter returns a function that prints version as plain text.
 func PlainVersionPrinter(version string) func(io.Writer) error {
 	return func(w io.Writer) error {
@@ -17,14 +52,115 @@ func PlainVersionPrinter(version string) func(io.Writer) error {
 	}
 }
 
+// PlainVersionPrinterFull returns a function that prints version, commit and date as plain text,
+// one per line ("version: v1.0.0"). Empty commit and date are omitted, as in JSONVersionPrinter.
+func PlainVersionPrinterFull(version, commit, date string) func(io.Writer) error {
+	return func(w io.Writer) error {
+		if w == nil {
+			return fmt.Errorf("nil writer received")
+		}
+		if _, err := fmt.Fprintf(w, "version: %s\n", version); err != nil {
+			return err
+		}
+		if commit != "" {
+			if _, err := fmt.Fprintf(w, "commit: %s\n", commit); err != nil {
+				return err
+			}
+		}
+		if date != "" {
+			if _, err := fmt.Fprintf(w, "date: %s\n", date); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
 // JSONVersionPrinter returns a function that prints version in JSON format.
 func JSONVersionPrinter(version, commit, date string) func(io.Writer) error {
+	data := versionInfo{Version: version, Commit: commit, Date: date}
+	return func(w io.Writer) error {
+		if w == nil {
+			return fmt.Errorf("nil writer received")
+		}
+		return json.NewEncoder(w).Encode(data)
+	}
+}
+
+// YAMLVersionPrinter returns a function that prints version in YAML format.
+// The fields are the same as for JSONVersionPrinter.
+func YAMLVersionPrinter(version, commit, date string) func(io.Writer) error {
+	data := versionInfo{Version: version, Commit: commit, Date: date}
+	return func(w io.Writer) error {
+		if w == nil {
+			return fmt.Errorf("nil writer received")
+		}
+		enc := yaml.NewEncoder(w)
+		if err := enc.Encode(data); err != nil {
+			return err
+		}
+		return enc.Close()
+	}
+}
+
+// SlogVersionPrinter returns a function that logs version as a structured record
+// (message "version", attributes version, commit and date) at the info level.
+// The writer is ignored, so any writer (or nil with WithAllowNilWriter) may be passed to Load.
+// Empty commit and date are omitted, as in JSONVersionPrinter.
+func SlogVersionPrinter(logger *slog.Logger, version, commit, date string) func(io.Writer) error {
+	return func(_ io.Writer) error {
+		if logger == nil {
+			return fmt.Errorf("nil logger received")
+		}
+		attrs := []slog.Attr{slog.String("version", version)}
+		if commit != "" {
+			attrs = append(attrs, slog.String("commit", commit))
+		}
+		if date != "" {
+			attrs = append(attrs, slog.String("date", date))
+		}
+		logger.LogAttrs(context.Background(), slog.LevelInfo, "version", attrs...)
+		return nil
+	}
+}
+
+// BuildInfoVersionPrinter returns a function that prints version in JSON format,
+// augmented with Go runtime and module build info.
+//
+// Module info (path, version, VCS revision and time) is taken from debug.ReadBuildInfo.
+// If build info is not available, the corresponding fields are omitted.
+func BuildInfoVersionPrinter(version string) func(io.Writer) error {
 	type info struct {
-		Version string `json:"version"`
-		Commit  string `json:"commit,omitempty"`
-		Date    string `json:"date,omitempty"`
+		Version       string `json:"version"`
+		GoVersion     string `json:"go_version"`
+		OS            string `json:"os"`
+		Arch          string `json:"arch"`
+		Module        string `json:"module,omitempty"`
+		ModuleVersion string `json:"module_version,omitempty"`
+		Revision      string `json:"revision,omitempty"`
+		RevisionTime  string `json:"revision_time,omitempty"`
+		Modified      bool   `json:"modified,omitempty"`
+	}
+	data := info{
+		Version:   version,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		data.Module = bi.Main.Path
+		data.ModuleVersion = bi.Main.Version
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				data.Revision = setting.Value
+			case "vcs.time":
+				data.RevisionTime = setting.Value
+			case "vcs.modified":
+				data.Modified = setting.Value == "true"
+			}
+		}
 	}
-	data := info{Version: version, Commit: commit, Date: date}
 	return func(w io.Writer) error {
 		if w == nil {
 			return fmt.Errorf("nil writer received")
@@ -32,3 +168,46 @@ func JSONVersionPrinter(version, commit, date string) func(io.Writer) error {
 		return json.NewEncoder(w).Encode(data)
 	}
 }
+
+// develVersion is the version, reported when the build info doesn't provide one (e.g., `go run` or tests).
+const develVersion = "(devel)"
+
+// DefaultVersionPrinter returns a function that prints version as plain text, taken from the build info,
+// so no version has to be passed via -ldflags.
+//
+// The version is the main module version (set by `go install module@version`), or the VCS revision
+// (with "-dirty" suffix for modified sources) for local builds. If neither is available, "(devel)" is printed.
+func DefaultVersionPrinter() func(io.Writer) error {
+	bi, ok := debug.ReadBuildInfo()
+	return PlainVersionPrinter(buildInfoVersion(bi, ok))
+}
+
+// buildInfoVersion returns the version from the build info bi (see DefaultVersionPrinter).
+// ok reports whether the build info is available.
+func buildInfoVersion(bi *debug.BuildInfo, ok bool) string {
+	if !ok || bi == nil {
+		return develVersion
+	}
+	if bi.Main.Version != "" && bi.Main.Version != develVersion {
+		return bi.Main.Version
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	switch {
+	case revision == "":
+		return develVersion
+	case modified:
+		return revision + "-dirty"
+	default:
+		return revision
+	}
+}
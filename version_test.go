@@ -0,0 +1,241 @@
+package configkit
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
+)
+
+type VersionSuite struct {
+	suite.Suite
+}
+
+func TestVersionSuite(t *testing.T) {
+	suite.Run(t, new(VersionSuite))
+}
+
+func (s *VersionSuite) TestYAMLVersionPrinter() {
+	testCases := []struct {
+		name     string
+		version  string
+		commit   string
+		date     string
+		expected versionInfo
+	}{
+		{
+			name:     "all fields",
+			version:  "v1.0.0",
+			commit:   "abc123",
+			date:     "2025-04-05",
+			expected: versionInfo{Version: "v1.0.0", Commit: "abc123", Date: "2025-04-05"},
+		},
+		{
+			name:     "version only",
+			version:  "v1.0.0",
+			expected: versionInfo{Version: "v1.0.0"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			buf := &bytes.Buffer{}
+			err := YAMLVersionPrinter(tC.version, tC.commit, tC.date)(buf)
+			s.Require().NoError(err, "expected nil, got error")
+
+			var actual versionInfo
+			s.Require().NoError(yaml.Unmarshal(buf.Bytes(), &actual), "unmarshal yaml")
+			s.Require().Equal(tC.expected, actual, "unexpected version info: got %+v, want %+v", actual, tC.expected)
+		})
+	}
+
+	s.Run("omits empty fields", func() {
+		buf := &bytes.Buffer{}
+		s.Require().NoError(YAMLVersionPrinter("v1.0.0", "", "")(buf), "expected nil, got error")
+		s.Require().Equal("version: v1.0.0\n", buf.String(), "unexpected output")
+	})
+
+	s.Run("nil writer", func() {
+		var w io.Writer
+		s.Require().Error(YAMLVersionPrinter("v1.0.0", "", "")(w), "expected error, got nil")
+	})
+}
+
+func (s *VersionSuite) TestBuildInfoVersionPrinter() {
+	s.Run("runtime fields", func() {
+		buf := &bytes.Buffer{}
+		s.Require().NoError(BuildInfoVersionPrinter("v1.0.0")(buf), "expected nil, got error")
+
+		var actual map[string]any
+		s.Require().NoError(json.Unmarshal(buf.Bytes(), &actual), "unmarshal json")
+		s.Require().Equal("v1.0.0", actual["version"], "unexpected version")
+		s.Require().Equal(runtime.Version(), actual["go_version"], "unexpected go version")
+		s.Require().Equal(runtime.GOOS, actual["os"], "unexpected os")
+		s.Require().Equal(runtime.GOARCH, actual["arch"], "unexpected arch")
+	})
+
+	s.Run("nil writer", func() {
+		var w io.Writer
+		s.Require().Error(BuildInfoVersionPrinter("v1.0.0")(w), "expected error, got nil")
+	})
+}
+
+func (s *VersionSuite) TestPlainVersionPrinterFull() {
+	testCases := []struct {
+		name     string
+		version  string
+		commit   string
+		date     string
+		expected string
+	}{
+		{
+			name:     "all fields",
+			version:  "v1.0.0",
+			commit:   "abc123",
+			date:     "2025-04-05",
+			expected: "version: v1.0.0\ncommit: abc123\ndate: 2025-04-05\n",
+		},
+		{
+			name:     "empty commit and date omitted",
+			version:  "v1.0.0",
+			expected: "version: v1.0.0\n",
+		},
+		{
+			name:     "empty commit omitted",
+			version:  "v1.0.0",
+			date:     "2025-04-05",
+			expected: "version: v1.0.0\ndate: 2025-04-05\n",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			buf := &bytes.Buffer{}
+			s.Require().NoError(PlainVersionPrinterFull(tC.version, tC.commit, tC.date)(buf), "expected nil, got error")
+			s.Require().Equal(tC.expected, buf.String(), "unexpected output")
+		})
+	}
+
+	s.Run("nil writer", func() {
+		var w io.Writer
+		s.Require().Error(PlainVersionPrinterFull("v1.0.0", "", "")(w), "expected error, got nil")
+	})
+}
+
+func (s *VersionSuite) TestSlogVersionPrinter() {
+	testCases := []struct {
+		name     string
+		version  string
+		commit   string
+		date     string
+		expected map[string]any
+	}{
+		{
+			name:     "all fields",
+			version:  "v1.0.0",
+			commit:   "abc123",
+			date:     "2025-04-05",
+			expected: map[string]any{"version": "v1.0.0", "commit": "abc123", "date": "2025-04-05"},
+		},
+		{
+			name:     "version only",
+			version:  "v1.0.0",
+			expected: map[string]any{"version": "v1.0.0"},
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewJSONHandler(buf, nil))
+			writer := &bytes.Buffer{}
+			err := SlogVersionPrinter(logger, tC.version, tC.commit, tC.date)(writer)
+			s.Require().NoError(err, "expected nil, got error")
+			s.Require().Empty(writer.String(), "writer must not be used")
+
+			var record map[string]any
+			s.Require().NoError(json.Unmarshal(buf.Bytes(), &record), "unmarshal log record")
+			s.Require().Equal("version", record[slog.MessageKey], "unexpected message")
+			s.Require().Equal(slog.LevelInfo.String(), record[slog.LevelKey], "unexpected level")
+			delete(record, slog.MessageKey)
+			delete(record, slog.LevelKey)
+			delete(record, slog.TimeKey)
+			s.Require().Equal(tC.expected, record, "unexpected attributes: got %v, want %v", record, tC.expected)
+		})
+	}
+
+	s.Run("nil writer", func() {
+		buf := &bytes.Buffer{}
+		logger := slog.New(slog.NewJSONHandler(buf, nil))
+		s.Require().NoError(SlogVersionPrinter(logger, "v1.0.0", "", "")(nil), "expected nil, got error")
+		s.Require().NotEmpty(buf.String(), "expected log record")
+	})
+
+	s.Run("nil logger", func() {
+		s.Require().Error(SlogVersionPrinter(nil, "v1.0.0", "", "")(&bytes.Buffer{}), "expected error, got nil")
+	})
+}
+
+func (s *VersionSuite) TestDefaultVersionPrinter() {
+	s.Run("non-empty output", func() {
+		buf := &bytes.Buffer{}
+		s.Require().NoError(DefaultVersionPrinter()(buf), "expected nil, got error")
+		s.Require().NotEmpty(strings.TrimSpace(buf.String()), "version must be printed")
+	})
+
+	testCases := []struct {
+		name     string
+		bi       *debug.BuildInfo
+		ok       bool
+		expected string
+	}{
+		{
+			name:     "missing build info",
+			expected: "(devel)",
+		},
+		{
+			name:     "module version",
+			bi:       &debug.BuildInfo{Main: debug.Module{Version: "v1.2.3"}},
+			ok:       true,
+			expected: "v1.2.3",
+		},
+		{
+			name: "vcs revision of devel build",
+			bi: &debug.BuildInfo{
+				Main:     debug.Module{Version: "(devel)"},
+				Settings: []debug.BuildSetting{{Key: "vcs.revision", Value: "abc123"}},
+			},
+			ok:       true,
+			expected: "abc123",
+		},
+		{
+			name: "modified vcs revision",
+			bi: &debug.BuildInfo{Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "abc123"},
+				{Key: "vcs.modified", Value: "true"},
+			}},
+			ok:       true,
+			expected: "abc123-dirty",
+		},
+		{
+			name:     "neither version nor revision",
+			bi:       &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			ok:       true,
+			expected: "(devel)",
+		},
+	}
+
+	for _, tC := range testCases {
+		s.Run(tC.name, func() {
+			actual := buildInfoVersion(tC.bi, tC.ok)
+			s.Require().Equal(tC.expected, actual, "unexpected version: got %q, want %q", actual, tC.expected)
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package configkit
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// WatchSignal reloads the config into cfg each time the process receives sig (e.g., syscall.SIGHUP).
+//
+// The config is read the same way Load does, including the built-in flags (e.g. --config), parsed by the last
+// successful load (see Reload), env variables and validation. On success the new config is copied into cfg.
+// On failure cfg keeps the previous config. Either way, onReload (if not nil) is called with the result.
+//
+// cfg must be a pointer to a struct, usually the one passed to Load. Note that cfg is updated in place
+// from a separate goroutine: synchronizing the access to it (e.g., in onReload) is up to the caller.
+//
+// The returned function stops watching and waits for the pending reload to finish. It is safe to call it
+// multiple times.
+func (l *Loader) WatchSignal(cfg any, sig os.Signal, onReload func(error)) (stop func(), err error) {
+	if err := validateTarget(cfg); err != nil {
+		return nil, err
+	}
+	if sig == nil {
+		return nil, fmt.Errorf("signal must be non-nil")
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	signal.Notify(signals, sig)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				err := l.reload(cfg)
+				if onReload != nil {
+					onReload(err)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			wg.Wait()
+		})
+	}, nil
+}
+
+// reload reads the config into a new value of the cfg type and copies it into cfg on success.
+func (l *Loader) reload(cfg any) error {
+	fresh, v, err := l.loadFresh(cfg)
+	if err != nil {
+		return err
+	}
+
+	reflect.ValueOf(cfg).Elem().Set(fresh.Elem())
+	l.rememberLoad(v, cfg)
+	return nil
+}
+
+// loadFresh reads the config into a new value of the cfg type the way Load does, including the built-in flags
+// of the last load, and validates it. The new value is returned along with the viper instance, it was loaded with.
+func (l *Loader) loadFresh(cfg any) (reflect.Value, *viper.Viper, error) {
+	fresh := reflect.New(reflect.TypeOf(cfg).Elem())
+	v, err := l.newReloadViper()
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	if err := l.loadConfig(v, fresh.Interface()); err != nil {
+		return reflect.Value{}, nil, fmt.Errorf("reload config: %w", err)
+	}
+	if err := l.validate(fresh.Interface()); err != nil {
+		return reflect.Value{}, nil, fmt.Errorf("reload config: %w", err)
+	}
+	return fresh, v, nil
+}